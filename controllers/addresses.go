@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+)
+
+// ConditionInvalidSpec is set on an NFDeployment whose spec the network function cannot be built from
+const ConditionInvalidSpec = "InvalidSpec"
+
+const (
+	// ReasonMissingInterface is the InvalidSpec reason for a required interface that is not in the spec
+	ReasonMissingInterface = "MissingInterface"
+	// ReasonInvalidAddress is the InvalidSpec reason for a malformed interface address or gateway
+	ReasonInvalidAddress = "InvalidAddress"
+)
+
+// InvalidSpecError reports an NFDeployment spec that cannot be rendered. It is not retried, the
+// NFDeployment gets an InvalidSpec condition with the reason until its spec is fixed.
+type InvalidSpecError struct {
+	Reason string
+	Err    error
+}
+
+func (e *InvalidSpecError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InvalidSpecError) Unwrap() error {
+	return e.Err
+}
+
+// IsInvalidSpec returns the InvalidSpecError in the chain of err, if any
+func IsInvalidSpec(err error) (*InvalidSpecError, bool) {
+	var invalid *InvalidSpecError
+	if errors.As(err, &invalid) {
+		return invalid, true
+	}
+	return nil, false
+}
+
+// invalidAddress returns an InvalidSpecError for a malformed address of an interface
+func invalidAddress(ifName, format string, args ...interface{}) error {
+	return &InvalidSpecError{
+		Reason: ReasonInvalidAddress,
+		Err:    fmt.Errorf("interface %s: %s", ifName, fmt.Sprintf(format, args...)),
+	}
+}
+
+// FindInterface returns the interface with the given name from the NFDeployment spec
+func FindInterface(nfDeployment *nephiov1alpha1.NFDeployment, name string) *nephiov1alpha1.InterfaceConfig {
+	for i := range nfDeployment.Spec.Interfaces {
+		if nfDeployment.Spec.Interfaces[i].Name == name {
+			return &nfDeployment.Spec.Interfaces[i]
+		}
+	}
+	return nil
+}
+
+// ParseInterfaceAddress parses an interface address in CIDR notation with its host bits, e.g. 10.0.0.5/24
+// or fd00::5/64, into the address and its prefix length
+func ParseInterfaceAddress(address string) (netip.Prefix, error) {
+	prefix, err := netip.ParsePrefix(address)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid address %q, expected an IP address with its prefix length", address)
+	}
+	return prefix, nil
+}
+
+// InterfaceAddresses returns the IPv4 and IPv6 addresses of an interface, either is invalid when not set.
+// Malformed addresses and addresses of the wrong family are reported as an InvalidSpecError.
+func InterfaceAddresses(iface *nephiov1alpha1.InterfaceConfig) (netip.Prefix, netip.Prefix, error) {
+	var v4, v6 netip.Prefix
+	if iface.IPv4 != nil && iface.IPv4.Address != "" {
+		prefix, err := ParseInterfaceAddress(iface.IPv4.Address)
+		if err != nil {
+			return v4, v6, invalidAddress(iface.Name, "ipv4: %v", err)
+		}
+		if !prefix.Addr().Is4() {
+			return v4, v6, invalidAddress(iface.Name, "ipv4: %s is not an IPv4 address", iface.IPv4.Address)
+		}
+		v4 = prefix
+	}
+	if iface.IPv6 != nil && iface.IPv6.Address != "" {
+		prefix, err := ParseInterfaceAddress(iface.IPv6.Address)
+		if err != nil {
+			return v4, v6, invalidAddress(iface.Name, "ipv6: %v", err)
+		}
+		if !prefix.Addr().Is6() || prefix.Addr().Is4In6() {
+			return v4, v6, invalidAddress(iface.Name, "ipv6: %s is not an IPv6 address", iface.IPv6.Address)
+		}
+		v6 = prefix
+	}
+	return v4, v6, nil
+}
+
+// InterfaceIPs returns the addresses of an interface of the NFDeployment without their prefix length, the
+// IPv4 address before the IPv6 one of a dual-stack interface. It is empty when the interface is missing.
+func InterfaceIPs(nfDeployment *nephiov1alpha1.NFDeployment, name string) ([]netip.Addr, error) {
+	iface := FindInterface(nfDeployment, name)
+	if iface == nil {
+		return nil, nil
+	}
+	v4, v6, err := InterfaceAddresses(iface)
+	if err != nil {
+		return nil, err
+	}
+	var ips []netip.Addr
+	if v4.IsValid() {
+		ips = append(ips, v4.Addr())
+	}
+	if v6.IsValid() {
+		ips = append(ips, v6.Addr())
+	}
+	return ips, nil
+}
+
+// InterfaceIP returns the address of an interface of the NFDeployment without its prefix length, the IPv4
+// address is preferred over the IPv6 one. The bool is false when the interface is missing or has no address.
+func InterfaceIP(nfDeployment *nephiov1alpha1.NFDeployment, name string) (netip.Addr, bool, error) {
+	ips, err := InterfaceIPs(nfDeployment, name)
+	if err != nil || len(ips) == 0 {
+		return netip.Addr{}, false, err
+	}
+	return ips[0], true, nil
+}
+
+// RequiredInterfaceIPs is InterfaceIPs for an interface the network function cannot run without,
+// a missing interface or address is reported as an InvalidSpecError
+func RequiredInterfaceIPs(nfDeployment *nephiov1alpha1.NFDeployment, name string) ([]netip.Addr, error) {
+	ips, err := InterfaceIPs(nfDeployment, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, &InvalidSpecError{
+			Reason: ReasonMissingInterface,
+			Err:    fmt.Errorf("interface %s with an IPv4 or IPv6 address is required", name),
+		}
+	}
+	return ips, nil
+}
+
+// RequiredInterfaceIP is RequiredInterfaceIPs returning the IPv4 address of a dual-stack interface
+func RequiredInterfaceIP(nfDeployment *nephiov1alpha1.NFDeployment, name string) (netip.Addr, error) {
+	ips, err := RequiredInterfaceIPs(nfDeployment, name)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return ips[0], nil
+}
+
+// ValidateInterfaces checks the addresses and gateways of all interfaces of the NFDeployment are well formed
+func ValidateInterfaces(nfDeployment *nephiov1alpha1.NFDeployment) error {
+	for i := range nfDeployment.Spec.Interfaces {
+		if err := ValidateInterface(&nfDeployment.Spec.Interfaces[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateInterface checks the addresses and gateways of an interface are well formed
+func ValidateInterface(iface *nephiov1alpha1.InterfaceConfig) error {
+	if _, _, err := InterfaceAddresses(iface); err != nil {
+		return err
+	}
+	if iface.IPv4 != nil && iface.IPv4.Gateway != nil && *iface.IPv4.Gateway != "" {
+		if gateway, err := netip.ParseAddr(*iface.IPv4.Gateway); err != nil || !gateway.Is4() {
+			return invalidAddress(iface.Name, "ipv4: invalid gateway %q", *iface.IPv4.Gateway)
+		}
+	}
+	if iface.IPv6 != nil && iface.IPv6.Gateway != nil && *iface.IPv6.Gateway != "" {
+		if gateway, err := netip.ParseAddr(*iface.IPv6.Gateway); err != nil || !gateway.Is6() {
+			return invalidAddress(iface.Name, "ipv6: invalid gateway %q", *iface.IPv6.Gateway)
+		}
+	}
+	return nil
+}
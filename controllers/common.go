@@ -7,17 +7,47 @@ import (
 	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
 )
 
+const (
+	// NRFURIKey is the annotation overriding the NRF URI an NF registers with
+	NRFURIKey = "nf.sdcore.io/nrf-uri"
+	// MongoDBURLKey is the annotation overriding the MongoDB URL of NFs backed by a database
+	MongoDBURLKey = "nf.sdcore.io/mongodb-url"
+	// UPFModeKey is the annotation selecting the BESS dataplane mode of a UPF
+	UPFModeKey = "nf.sdcore.io/upf-mode"
+
+	// NRFServiceName is the well-known Service name under which the NRF is reachable in a namespace
+	NRFServiceName = "nrf-service"
+	// NRFServicePort is the port of the well-known NRF Service
+	NRFServicePort = 8000
+
+	// DefaultMongoDBURL is the MongoDB URL used when none is configured
+	DefaultMongoDBURL = "mongodb://mongodb:27017"
+	// DefaultMongoDBName is the database used by the SD-Core NFs
+	DefaultMongoDBName = "free5gc"
+)
+
 // GetNamespacedName returns a namespaced name for a deployment
 func GetNamespacedName(nfDeployment *nephiov1alpha1.NFDeployment, suffix string) string {
 	return fmt.Sprintf("%s-%s", nfDeployment.Name, suffix)
 }
 
-// IsProviderSDCoreUPF returns true if the provider is sdcore UPF
-func IsProviderSDCoreUPF(provider string) bool {
-	return strings.EqualFold(provider, "upf.sdcore.io")
-}
-
 // IsProviderSDCore returns true if the provider is sdcore
 func IsProviderSDCore(provider string) bool {
-	return strings.EqualFold(provider, "sdcore") || strings.HasSuffix(strings.ToLower(provider), ".sdcore.io")
+	return strings.EqualFold(provider, "sdcore") || strings.HasSuffix(strings.ToLower(provider), sdcoreProviderSuffix)
+}
+
+// GetNRFURI returns the NRF URI an NF registers with and discovers other NFs from
+func GetNRFURI(nfDeployment *nephiov1alpha1.NFDeployment) string {
+	if uri, ok := nfDeployment.Annotations[NRFURIKey]; ok && uri != "" {
+		return uri
+	}
+	return fmt.Sprintf("http://%s:%d", NRFServiceName, NRFServicePort)
+}
+
+// GetMongoDBURL returns the MongoDB URL for NFs backed by a database
+func GetMongoDBURL(nfDeployment *nephiov1alpha1.NFDeployment) string {
+	if url, ok := nfDeployment.Annotations[MongoDBURLKey]; ok && url != "" {
+		return url
+	}
+	return DefaultMongoDBURL
 }
@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ConfigHashAnnotation is the pod template annotation holding the hash of the rendered NF configuration,
+	// a new hash rolls the pods of the Deployment
+	ConfigHashAnnotation = "nf.sdcore.io/config-hash"
+
+	// ConditionConfigApplied reports whether the pods of the NF run its current configuration
+	ConditionConfigApplied = "ConfigApplied"
+
+	// configHashLength is the number of hex digits of the hash kept in the annotation
+	configHashLength = 16
+)
+
+// ConfigHash returns the hash of the configuration the pods read at startup: the data of the ConfigMaps
+// and the CNI configuration of the NetworkAttachmentDefinitions among the objects
+func ConfigHash(objects []client.Object) string {
+	hash := sha256.New()
+	write := func(values ...string) {
+		for _, value := range values {
+			// JSON quoting keeps the boundaries between values unambiguous
+			_ = json.NewEncoder(hash).Encode(value)
+		}
+	}
+	for _, object := range objects {
+		switch object := object.(type) {
+		case *apiv1.ConfigMap:
+			write("ConfigMap", object.Name)
+			for _, key := range sortedKeys(object.Data) {
+				write(key, object.Data[key])
+			}
+			binaryKeys := make([]string, 0, len(object.BinaryData))
+			for key := range object.BinaryData {
+				binaryKeys = append(binaryKeys, key)
+			}
+			sort.Strings(binaryKeys)
+			for _, key := range binaryKeys {
+				write(key, string(object.BinaryData[key]))
+			}
+		case *unstructured.Unstructured:
+			if object.GroupVersionKind() != NetworkAttachmentDefinitionGVK {
+				continue
+			}
+			config, _, _ := unstructured.NestedString(object.Object, "spec", "config")
+			write("NetworkAttachmentDefinition", object.GetName(), config)
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:configHashLength]
+}
+
+// sortedKeys returns the keys of a string map in order
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// WithConfigHash stamps the configuration hash of the objects on the pod templates of their Deployments
+func WithConfigHash(objects []client.Object) []client.Object {
+	hash := ConfigHash(objects)
+	for _, object := range objects {
+		if deployment, ok := object.(*appsv1.Deployment); ok {
+			if deployment.Spec.Template.Annotations == nil {
+				deployment.Spec.Template.Annotations = map[string]string{}
+			}
+			deployment.Spec.Template.Annotations[ConfigHashAnnotation] = hash
+		}
+	}
+	return objects
+}
+
+// RolledOut returns true once every pod of the Deployment runs its current pod template
+func RolledOut(deployment *appsv1.Deployment) bool {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	status := deployment.Status
+	return status.ObservedGeneration >= deployment.Generation &&
+		status.UpdatedReplicas == replicas && status.Replicas == replicas
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	mccPattern   = regexp.MustCompile(`^[0-9]{3}$`)
+	mncPattern   = regexp.MustCompile(`^[0-9]{2,3}$`)
+	sdPattern    = regexp.MustCompile(`^[0-9a-fA-F]{6}$`)
+	amfIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{6}$`)
+)
+
+// maxTAC is the largest 24-bit 5GS tracking area code
+const maxTAC = 0xFFFFFF
+
+// PLMN is a public land mobile network identifier
+type PLMN struct {
+	// MCC is the three digit mobile country code
+	MCC string `json:"mcc"`
+	// MNC is the two or three digit mobile network code
+	MNC string `json:"mnc"`
+}
+
+// String returns the PLMN as MCC-MNC
+func (p PLMN) String() string {
+	return p.MCC + "-" + p.MNC
+}
+
+// TAI is a tracking area identity
+type TAI struct {
+	PLMN PLMN `json:"plmnId"`
+	// TAC is the tracking area code
+	TAC int `json:"tac"`
+}
+
+// GUAMI is a globally unique AMF identifier
+type GUAMI struct {
+	PLMN PLMN `json:"plmnId"`
+	// AMFID is the AMF region, set and pointer as six hex digits
+	AMFID string `json:"amfId"`
+}
+
+// Slice is an S-NSSAI served by the core
+type Slice struct {
+	// SST is the slice/service type
+	SST int `json:"sst"`
+	// SD is the slice differentiator as six hex digits
+	SD string `json:"sd"`
+	// DNNs are the data networks reachable on the slice
+	DNNs []string `json:"dnns,omitempty"`
+}
+
+// NetworkIdentity is the PLMN, tracking area, AMF and slice configuration shared by the NFs of a core
+type NetworkIdentity struct {
+	PLMNs  []PLMN
+	GUAMIs []GUAMI
+	TAIs   []TAI
+	Slices []Slice
+}
+
+// DefaultPLMN is the PLMN served unless configured through parameters
+var DefaultPLMN = PLMN{MCC: "208", MNC: "93"}
+
+const (
+	// DefaultAMFID is the AMF identifier of the GUAMIs unless configured through parameters
+	DefaultAMFID = "cafe00"
+	// DefaultTAC is the tracking area served in each PLMN unless configured through parameters
+	DefaultTAC = 1
+)
+
+// DefaultSlices are the S-NSSAIs served by every NF unless configured through parameters.
+// AMF, SMF, NSSF and PCF all render their slice configuration from the same list.
+var DefaultSlices = []Slice{
+	{SST: 1, SD: "010203", DNNs: []string{"internet"}},
+	{SST: 1, SD: "112233", DNNs: []string{"internet"}},
+}
+
+// DNNs returns the data networks reachable on any of the slices, in order of first appearance
+func (n NetworkIdentity) DNNs() []string {
+	var dnns []string
+	seen := map[string]bool{}
+	for _, slice := range n.Slices {
+		for _, dnn := range slice.DNNs {
+			if !seen[dnn] {
+				seen[dnn] = true
+				dnns = append(dnns, dnn)
+			}
+		}
+	}
+	return dnns
+}
+
+// Validate checks the identifier formats and that the identity has no duplicates and only
+// refers to PLMNs it serves
+func (n NetworkIdentity) Validate() error {
+	plmns := map[PLMN]bool{}
+	for i, plmn := range n.PLMNs {
+		if !mccPattern.MatchString(plmn.MCC) {
+			return fmt.Errorf("plmns[%d]: invalid MCC %q, expected 3 digits", i, plmn.MCC)
+		}
+		if !mncPattern.MatchString(plmn.MNC) {
+			return fmt.Errorf("plmns[%d]: invalid MNC %q, expected 2 or 3 digits", i, plmn.MNC)
+		}
+		if plmns[plmn] {
+			return fmt.Errorf("plmns[%d]: duplicate PLMN %s", i, plmn)
+		}
+		plmns[plmn] = true
+	}
+
+	guamis := map[GUAMI]bool{}
+	for i, guami := range n.GUAMIs {
+		if !plmns[guami.PLMN] {
+			return fmt.Errorf("guamis[%d]: PLMN %s is not served", i, guami.PLMN)
+		}
+		if !amfIDPattern.MatchString(guami.AMFID) {
+			return fmt.Errorf("guamis[%d]: invalid AMF ID %q, expected 6 hex digits", i, guami.AMFID)
+		}
+		if guamis[guami] {
+			return fmt.Errorf("guamis[%d]: duplicate GUAMI %s/%s", i, guami.PLMN, guami.AMFID)
+		}
+		guamis[guami] = true
+	}
+
+	tais := map[TAI]bool{}
+	for i, tai := range n.TAIs {
+		if !plmns[tai.PLMN] {
+			return fmt.Errorf("tais[%d]: PLMN %s is not served", i, tai.PLMN)
+		}
+		if tai.TAC < 0 || tai.TAC > maxTAC {
+			return fmt.Errorf("tais[%d]: TAC %d out of range 0-%d", i, tai.TAC, maxTAC)
+		}
+		if tais[tai] {
+			return fmt.Errorf("tais[%d]: duplicate TAI %s/%d", i, tai.PLMN, tai.TAC)
+		}
+		tais[tai] = true
+	}
+
+	slices := map[string]bool{}
+	for i, slice := range n.Slices {
+		if slice.SST < 0 || slice.SST > 255 {
+			return fmt.Errorf("slices[%d]: SST %d out of range 0-255", i, slice.SST)
+		}
+		if !sdPattern.MatchString(slice.SD) {
+			return fmt.Errorf("slices[%d]: invalid SD %q, expected 6 hex digits", i, slice.SD)
+		}
+		key := fmt.Sprintf("%d/%s", slice.SST, strings.ToLower(slice.SD))
+		if slices[key] {
+			return fmt.Errorf("slices[%d]: duplicate slice sst %d sd %s", i, slice.SST, slice.SD)
+		}
+		slices[key] = true
+	}
+	return nil
+}
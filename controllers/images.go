@@ -0,0 +1,307 @@
+package controllers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Image components are the container images of the network functions, each release bundle provides all of them
+const (
+	ImageAMF          = "amf"
+	ImageSMF          = "smf"
+	ImageNRF          = "nrf"
+	ImageAUSF         = "ausf"
+	ImageUDM          = "udm"
+	ImageUDR          = "udr"
+	ImagePCF          = "pcf"
+	ImageNSSF         = "nssf"
+	ImageUPFBESS      = "upf-bess"
+	ImageUPFPFCPIface = "upf-pfcpiface"
+)
+
+// DefaultRelease is the SD-Core release bundle deployed unless another one is selected
+const DefaultRelease = "1.6"
+
+// ImageRef is a container image reference, registry/repository:tag@digest. An empty registry is Docker Hub.
+type ImageRef struct {
+	Registry   string `json:"registry,omitempty"`
+	Repository string `json:"repository,omitempty"`
+	Tag        string `json:"tag,omitempty"`
+	Digest     string `json:"digest,omitempty"`
+}
+
+// String returns the image reference as used in a container spec
+func (i ImageRef) String() string {
+	var b strings.Builder
+	if i.Registry != "" {
+		b.WriteString(strings.TrimSuffix(i.Registry, "/"))
+		b.WriteString("/")
+	}
+	b.WriteString(i.Repository)
+	if i.Tag != "" {
+		b.WriteString(":")
+		b.WriteString(i.Tag)
+	}
+	if i.Digest != "" {
+		b.WriteString("@")
+		b.WriteString(i.Digest)
+	}
+	return b.String()
+}
+
+// override returns the image with the fields set in other replaced. A new tag drops the digest pinning
+// the previous one.
+func (i ImageRef) override(other ImageRef) ImageRef {
+	if other.Registry != "" {
+		i.Registry = other.Registry
+	}
+	if other.Repository != "" {
+		i.Repository = other.Repository
+	}
+	if other.Tag != "" {
+		i.Tag = other.Tag
+		i.Digest = ""
+	}
+	if other.Digest != "" {
+		i.Digest = other.Digest
+	}
+	return i
+}
+
+// ParseImageRef parses an image reference such as registry.example.com/omecproject/5gc-amf:rel-1.6.4.
+// The first path element is the registry when it holds a dot or a port, or is localhost.
+func ParseImageRef(value string) (ImageRef, error) {
+	var ref ImageRef
+	rest := value
+	if i := strings.Index(rest, "@"); i >= 0 {
+		ref.Digest = rest[i+1:]
+		rest = rest[:i]
+		if !strings.Contains(ref.Digest, ":") {
+			return ImageRef{}, fmt.Errorf("invalid image %q: digest %q has no algorithm", value, ref.Digest)
+		}
+	}
+	if i := strings.LastIndex(rest, ":"); i >= 0 && !strings.Contains(rest[i+1:], "/") {
+		ref.Tag = rest[i+1:]
+		rest = rest[:i]
+	}
+	if i := strings.Index(rest, "/"); i >= 0 {
+		first := rest[:i]
+		if strings.ContainsAny(first, ".:") || first == "localhost" {
+			ref.Registry = first
+			rest = rest[i+1:]
+		}
+	}
+	ref.Repository = rest
+	if ref.Repository == "" {
+		return ImageRef{}, fmt.Errorf("invalid image %q: missing repository", value)
+	}
+	return ref, nil
+}
+
+// Release is a known-good SD-Core release bundle: images of every component tested together
+type Release struct {
+	Name   string
+	Images map[string]ImageRef
+	// UpgradeFrom are the releases network functions can be upgraded from in place
+	UpgradeFrom []string
+}
+
+// releases is the compatibility matrix of the release bundles known to work together, by name. Every image is
+// pinned to a release tag, moving tags such as master-latest would change the bundle under the upgrade checks.
+var releases = map[string]Release{
+	"1.5": {
+		Name: "1.5",
+		Images: map[string]ImageRef{
+			ImageAMF:          {Registry: "registry.opennetworking.org/docker.io", Repository: "omecproject/5gc-amf", Tag: "rel-1.5.1"},
+			ImageSMF:          {Registry: "registry.opennetworking.org/docker.io", Repository: "omecproject/5gc-smf", Tag: "rel-1.5.1"},
+			ImageNRF:          {Repository: "omecproject/5gc-nrf", Tag: "rel-1.5.1"},
+			ImageAUSF:         {Repository: "omecproject/5gc-ausf", Tag: "rel-1.5.1"},
+			ImageUDM:          {Repository: "omecproject/5gc-udm", Tag: "rel-1.5.1"},
+			ImageUDR:          {Repository: "omecproject/5gc-udr", Tag: "rel-1.5.1"},
+			ImagePCF:          {Repository: "omecproject/5gc-pcf", Tag: "rel-1.5.1"},
+			ImageNSSF:         {Repository: "omecproject/5gc-nssf", Tag: "rel-1.5.1"},
+			ImageUPFBESS:      {Repository: "omecproject/upf-epc-bess", Tag: "rel-1.5.0"},
+			ImageUPFPFCPIface: {Repository: "omecproject/upf-epc-pfcpiface", Tag: "rel-1.5.0"},
+		},
+	},
+	"1.6": {
+		Name: "1.6",
+		Images: map[string]ImageRef{
+			ImageAMF:          {Registry: "registry.opennetworking.org/docker.io", Repository: "omecproject/5gc-amf", Tag: "rel-1.6.4"},
+			ImageSMF:          {Registry: "registry.opennetworking.org/docker.io", Repository: "omecproject/5gc-smf", Tag: "rel-1.6.2"},
+			ImageNRF:          {Repository: "omecproject/5gc-nrf", Tag: "rel-1.6.3"},
+			ImageAUSF:         {Repository: "omecproject/5gc-ausf", Tag: "rel-1.6.2"},
+			ImageUDM:          {Repository: "omecproject/5gc-udm", Tag: "rel-1.6.2"},
+			ImageUDR:          {Repository: "omecproject/5gc-udr", Tag: "rel-1.6.2"},
+			ImagePCF:          {Repository: "omecproject/5gc-pcf", Tag: "rel-1.6.2"},
+			ImageNSSF:         {Repository: "omecproject/5gc-nssf", Tag: "rel-1.6.2"},
+			ImageUPFBESS:      {Repository: "omecproject/upf-epc-bess", Tag: "rel-2.0.1"},
+			ImageUPFPFCPIface: {Repository: "omecproject/upf-epc-pfcpiface", Tag: "rel-2.0.1"},
+		},
+		UpgradeFrom: []string{"1.5"},
+	},
+}
+
+// LookupRelease returns the release bundle with the given name
+func LookupRelease(name string) (Release, bool) {
+	release, ok := releases[name]
+	return release, ok
+}
+
+// ReleaseNames returns the names of the known release bundles in order
+func ReleaseNames() []string {
+	names := make([]string, 0, len(releases))
+	for name := range releases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CheckUpgrade checks network functions of a release can be upgraded in place to another release
+func CheckUpgrade(from, to string) error {
+	if from == to {
+		return nil
+	}
+	release, ok := LookupRelease(to)
+	if !ok {
+		return fmt.Errorf("unknown release %q, known releases are %v", to, ReleaseNames())
+	}
+	for _, name := range release.UpgradeFrom {
+		if name == from {
+			return nil
+		}
+	}
+	return fmt.Errorf("release %s cannot be upgraded to release %s", from, to)
+}
+
+// ImageReleases returns the names of the release bundles providing the image, matched by repository and tag
+// regardless of the registry, in order. Registries holding the repository under a path, such as
+// registry.opennetworking.org/docker.io, match too.
+func ImageReleases(image string) []string {
+	ref, err := ParseImageRef(image)
+	if err != nil || ref.Tag == "" {
+		return nil
+	}
+	var names []string
+	for _, name := range ReleaseNames() {
+		for _, bundled := range releases[name].Images {
+			sameRepository := ref.Repository == bundled.Repository ||
+				strings.HasSuffix(ref.Repository, "/"+bundled.Repository)
+			if sameRepository && bundled.Tag == ref.Tag {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// CheckImageUpgrade checks a container can be moved from one image to another in place. An image of another
+// release bundle, e.g. a component override pinning the tag of another release, must be upgradable from the
+// release of the running image. Images of no release bundle, such as patch builds, are not checked.
+func CheckImageUpgrade(from, to string) error {
+	fromReleases, toReleases := ImageReleases(from), ImageReleases(to)
+	if len(fromReleases) == 0 || len(toReleases) == 0 {
+		return nil
+	}
+	for _, toRelease := range toReleases {
+		for _, fromRelease := range fromReleases {
+			if CheckUpgrade(fromRelease, toRelease) == nil {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("image %s of release %s cannot be upgraded to image %s of release %s",
+		from, strings.Join(fromReleases, ", "), to, strings.Join(toReleases, ", "))
+}
+
+// ImageSettings select the images of the network functions: a release bundle, a registry replacing the
+// registry of every image and per-component overrides, e.g.
+//
+//	release: "1.6"
+//	registry: registry.example.com
+//	components:
+//	  amf: {tag: rel-1.6.5}
+type ImageSettings struct {
+	// Release is the release bundle, DefaultRelease when empty
+	Release string `json:"release,omitempty"`
+	// Registry replaces the registry of every image
+	Registry string `json:"registry,omitempty"`
+	// Components overrides the images of single components, taking precedence over the registry
+	Components map[string]ImageRef `json:"components,omitempty"`
+}
+
+// Merge returns the settings with other merged in, values in other take precedence
+func (s ImageSettings) Merge(other ImageSettings) ImageSettings {
+	merged := ImageSettings{Release: s.Release, Registry: s.Registry}
+	if other.Release != "" {
+		merged.Release = other.Release
+	}
+	if other.Registry != "" {
+		merged.Registry = other.Registry
+	}
+	for _, components := range []map[string]ImageRef{s.Components, other.Components} {
+		for component, ref := range components {
+			if merged.Components == nil {
+				merged.Components = map[string]ImageRef{}
+			}
+			merged.Components[component] = merged.Components[component].override(ref)
+		}
+	}
+	return merged
+}
+
+// Validate checks the release bundle is known and the overridden components exist
+func (s ImageSettings) Validate() error {
+	release, ok := LookupRelease(s.GetRelease())
+	if !ok {
+		return fmt.Errorf("unknown release %q, known releases are %v", s.Release, ReleaseNames())
+	}
+	for component := range s.Components {
+		if _, ok := release.Images[component]; !ok {
+			return fmt.Errorf("unknown image component %q", component)
+		}
+	}
+	return nil
+}
+
+// GetRelease returns the name of the selected release bundle
+func (s ImageSettings) GetRelease() string {
+	if s.Release == "" {
+		return DefaultRelease
+	}
+	return s.Release
+}
+
+// Image returns the image of a component: the image of the release bundle with the registry and the
+// component override applied
+func (s ImageSettings) Image(component string) ImageRef {
+	release, ok := LookupRelease(s.GetRelease())
+	if !ok {
+		release, _ = LookupRelease(DefaultRelease)
+	}
+	image := release.Images[component]
+	if s.Registry != "" {
+		image.Registry = s.Registry
+	}
+	return image.override(s.Components[component])
+}
+
+// ParseImageOverrides parses component=image overrides, e.g. amf=registry.example.com/omecproject/5gc-amf:rel-1.6.5
+func ParseImageOverrides(values []string) (map[string]ImageRef, error) {
+	overrides := map[string]ImageRef{}
+	for _, value := range values {
+		component, image, ok := strings.Cut(strings.TrimSpace(value), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid image override %q, expected <component>=<image>", value)
+		}
+		ref, err := ParseImageRef(image)
+		if err != nil {
+			return nil, err
+		}
+		overrides[component] = ref
+	}
+	return overrides, nil
+}
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestReleasesPinned checks every image of the release bundles is pinned to a release tag
+func TestReleasesPinned(t *testing.T) {
+	for _, name := range ReleaseNames() {
+		for component, image := range releases[name].Images {
+			if !strings.HasPrefix(image.Tag, "rel-") {
+				t.Errorf("release %s: image %s of %s is not pinned to a release tag", name, image, component)
+			}
+		}
+	}
+}
+
+// TestCheckImageUpgrade checks images of another release bundle are only upgraded from the releases they
+// upgrade from, and images of no bundle are not checked
+func TestCheckImageUpgrade(t *testing.T) {
+	previous, _ := LookupRelease("1.5")
+	current, _ := LookupRelease(DefaultRelease)
+	tests := []struct {
+		name     string
+		from, to string
+		valid    bool
+	}{
+		{name: "upgrade", from: previous.Images[ImageAMF].String(), to: current.Images[ImageAMF].String(), valid: true},
+		{name: "downgrade", from: current.Images[ImageAMF].String(), to: previous.Images[ImageAMF].String()},
+		{name: "mirror", from: previous.Images[ImageNRF].String(), to: "registry.example.com/" + current.Images[ImageNRF].String(),
+			valid: true},
+		{name: "patch build", from: current.Images[ImageSMF].String(), to: "omecproject/5gc-smf:fix-pfcp", valid: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := CheckImageUpgrade(test.from, test.to)
+			if (err == nil) != test.valid {
+				t.Errorf("CheckImageUpgrade(%s, %s) = %v, want valid %t", test.from, test.to, err, test.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"fmt"
+
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+	apiv1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// sbiBindIPv4 is the SBI listen address of single-stack IPv4 network functions
+	sbiBindIPv4 = "0.0.0.0"
+	// sbiBindDualStack is the SBI listen address once IPv6 is served, it accepts IPv4 connections too
+	sbiBindDualStack = "::"
+
+	// ReasonIPFamilyConflict is the InvalidSpec reason for IP families that no longer include the primary
+	// family of an existing Service, which Kubernetes does not allow to change
+	ReasonIPFamilyConflict = "IPFamilyConflict"
+)
+
+// GetIPFamilies returns the IP families served by the network function, primary family first. The ipFamilies
+// parameter takes precedence, otherwise the families are those of the interface addresses of the NFDeployment
+// in order of appearance. The result is empty when neither is set, leaving the choice to the cluster.
+func GetIPFamilies(nfDeployment *nephiov1alpha1.NFDeployment, params *Parameters) []apiv1.IPFamily {
+	if params != nil && len(params.IPFamilies) > 0 {
+		return params.IPFamilies
+	}
+	var families []apiv1.IPFamily
+	for _, iface := range nfDeployment.Spec.Interfaces {
+		if iface.IPv4 != nil && iface.IPv4.Address != "" {
+			families = appendIPFamily(families, apiv1.IPv4Protocol)
+		}
+		if iface.IPv6 != nil && iface.IPv6.Address != "" {
+			families = appendIPFamily(families, apiv1.IPv6Protocol)
+		}
+	}
+	return families
+}
+
+// appendIPFamily appends the family unless it is already in the list
+func appendIPFamily(families []apiv1.IPFamily, family apiv1.IPFamily) []apiv1.IPFamily {
+	for _, existing := range families {
+		if existing == family {
+			return families
+		}
+	}
+	return append(families, family)
+}
+
+// validateIPFamilies checks the ipFamilies parameter lists IPv4 and IPv6 at most once each
+func validateIPFamilies(families []apiv1.IPFamily) error {
+	seen := map[apiv1.IPFamily]bool{}
+	for i, family := range families {
+		if family != apiv1.IPv4Protocol && family != apiv1.IPv6Protocol {
+			return fmt.Errorf("ipFamilies[%d]: unknown IP family %q, expected IPv4 or IPv6", i, family)
+		}
+		if seen[family] {
+			return fmt.Errorf("ipFamilies[%d]: duplicate IP family %s", i, family)
+		}
+		seen[family] = true
+	}
+	return nil
+}
+
+// SBIBindAddress returns the wildcard address the SBI server of the network function listens on
+func SBIBindAddress(families []apiv1.IPFamily) string {
+	for _, family := range families {
+		if family == apiv1.IPv6Protocol {
+			return sbiBindDualStack
+		}
+	}
+	return sbiBindIPv4
+}
+
+// SetServiceIPFamilies sets the IP families of a Service: single-stack for one family and PreferDualStack for
+// two, so that dual-stack network functions still deploy on single-stack clusters. Without families the
+// Service keeps the cluster defaults.
+func SetServiceIPFamilies(service *apiv1.Service, families []apiv1.IPFamily) {
+	if len(families) == 0 {
+		return
+	}
+	policy := apiv1.IPFamilyPolicySingleStack
+	if len(families) > 1 {
+		policy = apiv1.IPFamilyPolicyPreferDualStack
+	}
+	service.Spec.IPFamilyPolicy = &policy
+	service.Spec.IPFamilies = append([]apiv1.IPFamily(nil), families...)
+}
+
+// KeepPrimaryIPFamily keeps the primary IP family of the existing Service on the desired Service, the secondary
+// family may still be added or removed. Desired families without the existing primary family are reported as
+// an InvalidSpecError, the Service has to be deleted to change it.
+func KeepPrimaryIPFamily(service, existing *apiv1.Service) error {
+	families := service.Spec.IPFamilies
+	if len(families) == 0 || len(existing.Spec.IPFamilies) == 0 {
+		return nil
+	}
+	primary := existing.Spec.IPFamilies[0]
+	for i, family := range families {
+		if family == primary {
+			families[0], families[i] = families[i], families[0]
+			return nil
+		}
+	}
+	return &InvalidSpecError{
+		Reason: ReasonIPFamilyConflict,
+		Err: fmt.Errorf("IP families %v do not include %s, the primary IP family of Service %s, "+
+			"which cannot be changed: delete the Service to change it", families, primary, existing.Name),
+	}
+}
+
+// WithIPFamilies sets the IP families of the NFDeployment on the Services among the objects
+func WithIPFamilies(nfDeployment *nephiov1alpha1.NFDeployment, params *Parameters, objects []client.Object) []client.Object {
+	families := GetIPFamilies(nfDeployment, params)
+	for _, object := range objects {
+		if service, ok := object.(*apiv1.Service); ok {
+			SetServiceIPFamilies(service, families)
+		}
+	}
+	return objects
+}
@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestKeepPrimaryIPFamily checks the primary IP family of an existing Service is kept and a change of it is
+// reported as InvalidSpec
+func TestKeepPrimaryIPFamily(t *testing.T) {
+	ipv4, ipv6 := apiv1.IPv4Protocol, apiv1.IPv6Protocol
+	tests := []struct {
+		name     string
+		existing []apiv1.IPFamily
+		desired  []apiv1.IPFamily
+		want     []apiv1.IPFamily
+		conflict bool
+	}{
+		{name: "unchanged", existing: []apiv1.IPFamily{ipv4}, desired: []apiv1.IPFamily{ipv4}, want: []apiv1.IPFamily{ipv4}},
+		{name: "secondary added", existing: []apiv1.IPFamily{ipv4}, desired: []apiv1.IPFamily{ipv4, ipv6},
+			want: []apiv1.IPFamily{ipv4, ipv6}},
+		{name: "order swapped", existing: []apiv1.IPFamily{ipv4, ipv6}, desired: []apiv1.IPFamily{ipv6, ipv4},
+			want: []apiv1.IPFamily{ipv4, ipv6}},
+		{name: "secondary removed", existing: []apiv1.IPFamily{ipv6, ipv4}, desired: []apiv1.IPFamily{ipv6},
+			want: []apiv1.IPFamily{ipv6}},
+		{name: "primary removed", existing: []apiv1.IPFamily{ipv4}, desired: []apiv1.IPFamily{ipv6}, conflict: true},
+		{name: "cluster defaults", existing: []apiv1.IPFamily{ipv4}, want: nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service := &apiv1.Service{ObjectMeta: metav1.ObjectMeta{Name: "amf-service"}}
+			SetServiceIPFamilies(service, test.desired)
+			existing := &apiv1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "amf-service"},
+				Spec:       apiv1.ServiceSpec{IPFamilies: test.existing},
+			}
+			err := KeepPrimaryIPFamily(service, existing)
+			if test.conflict {
+				if invalid, ok := IsInvalidSpec(err); !ok || invalid.Reason != ReasonIPFamilyConflict {
+					t.Errorf("got %v, want an %s InvalidSpecError", err, ReasonIPFamilyConflict)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(service.Spec.IPFamilies, test.want) {
+				t.Errorf("got IP families %v, want %v", service.Spec.IPFamilies, test.want)
+			}
+		})
+	}
+}
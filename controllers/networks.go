@@ -0,0 +1,319 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// NetworksAnnotation is the Multus pod annotation selecting the NetworkAttachmentDefinitions to attach
+	NetworksAnnotation = "k8s.v1.cni.cncf.io/networks"
+	// resourceNameAnnotation binds a NetworkAttachmentDefinition to a device plugin resource
+	resourceNameAnnotation = "k8s.v1.cni.cncf.io/resourceName"
+
+	// CNI plugins a network attachment can use
+	CNITypeMacvlan = "macvlan"
+	CNITypeIPvlan  = "ipvlan"
+	CNITypeSRIOV   = "sriov"
+
+	// DefaultNetworkMaster is the host interface macvlan and ipvlan attachments use when none is configured
+	DefaultNetworkMaster = "eth0"
+
+	cniVersion = "0.3.1"
+
+	// ConditionNetworksUnavailable is set on an NFDeployment whose interfaces cannot be attached because the
+	// NetworkAttachmentDefinition CRD of Multus is not installed
+	ConditionNetworksUnavailable = "NetworksUnavailable"
+)
+
+// NetworkAttachmentDefinitionGVK is the group, version and kind of the Multus NetworkAttachmentDefinition
+var NetworkAttachmentDefinitionGVK = schema.GroupVersionKind{
+	Group:   "k8s.cni.cncf.io",
+	Version: "v1",
+	Kind:    "NetworkAttachmentDefinition",
+}
+
+// AttachmentParameters select how an NF interface is attached to the host network
+type AttachmentParameters struct {
+	// CNIType is the CNI plugin, macvlan (default), ipvlan or sriov
+	CNIType string `json:"cniType,omitempty"`
+	// Master is the host interface macvlan and ipvlan attach to, the VLAN subinterface
+	// <master>.<vlanID> is used when the interface has a VLAN ID
+	Master string `json:"master,omitempty"`
+	// ResourceName is the SR-IOV device plugin resource the VF is allocated from
+	ResourceName string `json:"resourceName,omitempty"`
+}
+
+// NetworkParameters are the network attachment settings, the embedded defaults apply to
+// every interface and can be overridden per interface name
+type NetworkParameters struct {
+	AttachmentParameters
+	// Interfaces overrides the defaults for an interface, e.g. n3
+	Interfaces map[string]AttachmentParameters `json:"interfaces,omitempty"`
+}
+
+// GetAttachment returns the attachment settings of an interface
+func (p *Parameters) GetAttachment(ifName string) AttachmentParameters {
+	attachment := AttachmentParameters{CNIType: CNITypeMacvlan, Master: DefaultNetworkMaster}
+	if p == nil || p.Networks == nil {
+		return attachment
+	}
+	attachment.merge(p.Networks.AttachmentParameters)
+	attachment.merge(p.Networks.Interfaces[ifName])
+	return attachment
+}
+
+// merge overrides the settings set in other
+func (a *AttachmentParameters) merge(other AttachmentParameters) {
+	if other.CNIType != "" {
+		a.CNIType = other.CNIType
+	}
+	if other.Master != "" {
+		a.Master = other.Master
+	}
+	if other.ResourceName != "" {
+		a.ResourceName = other.ResourceName
+	}
+}
+
+// merge merges other into the network parameters, values in other take precedence
+func (n *NetworkParameters) merge(other *NetworkParameters) {
+	n.AttachmentParameters.merge(other.AttachmentParameters)
+	for ifName, attachment := range other.Interfaces {
+		if n.Interfaces == nil {
+			n.Interfaces = map[string]AttachmentParameters{}
+		}
+		existing := n.Interfaces[ifName]
+		existing.merge(attachment)
+		n.Interfaces[ifName] = existing
+	}
+}
+
+// cniConfig is the CNI configuration of a NetworkAttachmentDefinition
+type cniConfig struct {
+	CNIVersion string     `json:"cniVersion"`
+	Type       string     `json:"type"`
+	Master     string     `json:"master,omitempty"`
+	Mode       string     `json:"mode,omitempty"`
+	VLAN       *uint16    `json:"vlan,omitempty"`
+	IPAM       ipamConfig `json:"ipam"`
+}
+
+// ipamConfig is the static IPAM configuration of an attachment
+type ipamConfig struct {
+	Type      string        `json:"type"`
+	Addresses []ipamAddress `json:"addresses,omitempty"`
+	Routes    []ipamRoute   `json:"routes,omitempty"`
+}
+
+type ipamAddress struct {
+	Address string `json:"address"`
+	Gateway string `json:"gateway,omitempty"`
+}
+
+type ipamRoute struct {
+	Dst string `json:"dst"`
+	GW  string `json:"gw,omitempty"`
+}
+
+// addAddress adds a static address and, through its gateway, the routes to the peers of the same IP family
+func (c *ipamConfig) addAddress(address netip.Prefix, gateway *string, peers []netip.Prefix) {
+	entry := ipamAddress{Address: address.String()}
+	if gateway != nil && *gateway != "" {
+		entry.Gateway = *gateway
+		for _, peer := range peers {
+			if peer.Addr().Is4() == address.Addr().Is4() {
+				c.Routes = append(c.Routes, ipamRoute{Dst: peer.String(), GW: *gateway})
+			}
+		}
+	}
+	c.Addresses = append(c.Addresses, entry)
+}
+
+// networkSelection is an entry of the Multus networks annotation
+type networkSelection struct {
+	Name      string `json:"name"`
+	Interface string `json:"interface"`
+}
+
+// attachedInterfaces returns the interfaces of the NFDeployment that get a network attachment
+func attachedInterfaces(nfDeployment *nephiov1alpha1.NFDeployment) []nephiov1alpha1.InterfaceConfig {
+	var interfaces []nephiov1alpha1.InterfaceConfig
+	for _, iface := range nfDeployment.Spec.Interfaces {
+		if (iface.IPv4 != nil && iface.IPv4.Address != "") || (iface.IPv6 != nil && iface.IPv6.Address != "") {
+			interfaces = append(interfaces, iface)
+		}
+	}
+	return interfaces
+}
+
+// BuildNetworkAttachments builds a NetworkAttachmentDefinition with static IPAM for each interface of the
+// NFDeployment, with both addresses of dual-stack interfaces. Routes to the peers of the network instances
+// holding the interface go through the gateway of their IP family.
+func BuildNetworkAttachments(nfDeployment *nephiov1alpha1.NFDeployment, params *Parameters) ([]client.Object, error) {
+	var objects []client.Object
+	for _, iface := range attachedInterfaces(nfDeployment) {
+		attachment := params.GetAttachment(iface.Name)
+
+		v4, v6, err := InterfaceAddresses(&iface)
+		if err != nil {
+			return nil, err
+		}
+
+		config := cniConfig{
+			CNIVersion: cniVersion,
+			Type:       attachment.CNIType,
+			IPAM:       ipamConfig{Type: "static"},
+		}
+		peers := PeerPrefixes(nfDeployment, iface.Name)
+		if v4.IsValid() {
+			config.IPAM.addAddress(v4, iface.IPv4.Gateway, peers)
+		}
+		if v6.IsValid() {
+			config.IPAM.addAddress(v6, iface.IPv6.Gateway, peers)
+		}
+
+		switch attachment.CNIType {
+		case CNITypeMacvlan, CNITypeIPvlan:
+			config.Master = attachment.Master
+			if iface.VLANID != nil {
+				config.Master = fmt.Sprintf("%s.%d", attachment.Master, *iface.VLANID)
+			}
+			config.Mode = "bridge"
+			if attachment.CNIType == CNITypeIPvlan {
+				config.Mode = "l2"
+			}
+		case CNITypeSRIOV:
+			if attachment.ResourceName == "" {
+				return nil, fmt.Errorf("interface %s: sriov attachment requires a resourceName", iface.Name)
+			}
+			config.VLAN = iface.VLANID
+		default:
+			return nil, fmt.Errorf("interface %s: unsupported CNI type %q", iface.Name, attachment.CNIType)
+		}
+
+		rendered, err := json.Marshal(config)
+		if err != nil {
+			return nil, err
+		}
+
+		nad := new(unstructured.Unstructured)
+		nad.SetGroupVersionKind(NetworkAttachmentDefinitionGVK)
+		nad.SetName(NetworkAttachmentName(nfDeployment, iface.Name))
+		nad.SetNamespace(nfDeployment.Namespace)
+		if attachment.CNIType == CNITypeSRIOV {
+			nad.SetAnnotations(map[string]string{resourceNameAnnotation: attachment.ResourceName})
+		}
+		nad.Object["spec"] = map[string]interface{}{"config": string(rendered)}
+		objects = append(objects, nad)
+	}
+	return objects, nil
+}
+
+// NetworkAttachmentName returns the name of the NetworkAttachmentDefinition of an interface
+func NetworkAttachmentName(nfDeployment *nephiov1alpha1.NFDeployment, ifName string) string {
+	return GetNamespacedName(nfDeployment, ifName)
+}
+
+// AttachNetworks sets the Multus networks annotation on the pod template so every interface of the
+// NFDeployment is attached under its own name, and requests the SR-IOV VFs on the first container
+func AttachNetworks(template *apiv1.PodTemplateSpec, nfDeployment *nephiov1alpha1.NFDeployment, params *Parameters) error {
+	interfaces := attachedInterfaces(nfDeployment)
+	if len(interfaces) == 0 {
+		return nil
+	}
+
+	selections := make([]networkSelection, 0, len(interfaces))
+	for _, iface := range interfaces {
+		selections = append(selections, networkSelection{
+			Name:      NetworkAttachmentName(nfDeployment, iface.Name),
+			Interface: iface.Name,
+		})
+
+		attachment := params.GetAttachment(iface.Name)
+		if attachment.CNIType == CNITypeSRIOV && len(template.Spec.Containers) > 0 {
+			addResourceCount(&template.Spec.Containers[0], apiv1.ResourceName(attachment.ResourceName))
+		}
+	}
+
+	networks, err := json.Marshal(selections)
+	if err != nil {
+		return err
+	}
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	template.Annotations[NetworksAnnotation] = string(networks)
+	return nil
+}
+
+// WithNetworkAttachments attaches the NFDeployment interfaces to the Deployments among the objects and
+// returns the objects preceded by the NetworkAttachmentDefinitions they reference
+func WithNetworkAttachments(nfDeployment *nephiov1alpha1.NFDeployment, params *Parameters, objects []client.Object) ([]client.Object, error) {
+	attachments, err := BuildNetworkAttachments(nfDeployment, params)
+	if err != nil {
+		return nil, err
+	}
+	for _, object := range objects {
+		if deployment, ok := object.(*appsv1.Deployment); ok {
+			if err := AttachNetworks(&deployment.Spec.Template, nfDeployment, params); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return append(attachments, objects...), nil
+}
+
+// PeerPrefixes returns the IPv4 and IPv6 prefixes of the peers in the network instances holding an interface
+func PeerPrefixes(nfDeployment *nephiov1alpha1.NFDeployment, ifName string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, networkInstance := range nfDeployment.Spec.NetworkInstances {
+		if !containsString(networkInstance.Interfaces, ifName) {
+			continue
+		}
+		for _, peer := range networkInstance.Peers {
+			if peer.IPv4 != nil {
+				if prefix, err := ParseInterfaceAddress(peer.IPv4.Address); err == nil {
+					prefixes = append(prefixes, prefix.Masked())
+				}
+			}
+			if peer.IPv6 != nil {
+				if prefix, err := ParseInterfaceAddress(peer.IPv6.Address); err == nil {
+					prefixes = append(prefixes, prefix.Masked())
+				}
+			}
+		}
+	}
+	return prefixes
+}
+
+// addResourceCount requests one more unit of a device plugin resource on the container
+func addResourceCount(container *apiv1.Container, name apiv1.ResourceName) {
+	for _, list := range []*apiv1.ResourceList{&container.Resources.Requests, &container.Resources.Limits} {
+		if *list == nil {
+			*list = apiv1.ResourceList{}
+		}
+		count := (*list)[name]
+		count.Add(resource.MustParse("1"))
+		(*list)[name] = count
+	}
+}
+
+// containsString returns true if the value is in the list
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
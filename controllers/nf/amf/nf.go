@@ -0,0 +1,47 @@
+package amf
+
+import (
+	"github.com/RohitRathore1/sdcore-operator/controllers"
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func init() {
+	controllers.Register(networkFunction{})
+}
+
+// networkFunction implements controllers.NetworkFunction for the AMF
+type networkFunction struct{}
+
+// Type returns the AMF NF type
+func (networkFunction) Type() controllers.NFType {
+	return controllers.NFTypeAMF
+}
+
+// MatchesProvider returns true for the amf.sdcore.io provider
+func (networkFunction) MatchesProvider(provider string) bool {
+	return controllers.MatchesSDCoreProvider(controllers.NFTypeAMF, provider)
+}
+
+// ValidateSpec requires the N2 interface the gNBs connect to
+func (networkFunction) ValidateSpec(nfDeployment *nephiov1alpha1.NFDeployment) field.ErrorList {
+	return controllers.RequireInterfaces(nfDeployment, "n2")
+}
+
+// BuildResources returns the ConfigMap, Deployment and Services of the AMF
+func (networkFunction) BuildResources(nfDeployment *nephiov1alpha1.NFDeployment, params *controllers.Parameters) ([]client.Object, error) {
+	configMap, err := buildConfigMap(nfDeployment, params)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := []client.Object{
+		configMap,
+		buildDeployment(nfDeployment, params),
+	}
+	for _, service := range buildServices(nfDeployment) {
+		objects = append(objects, service)
+	}
+	return objects, nil
+}
@@ -1,31 +1,25 @@
 package amf
 
 import (
-	"context"
 	"fmt"
+	"net/netip"
+	"strings"
 
 	"github.com/RohitRathore1/sdcore-operator/controllers"
 	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
 	appsv1 "k8s.io/api/apps/v1"
 	apiv1 "k8s.io/api/core/v1"
-	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
-	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
-	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 const (
 	// AMF container names
 	amfContainerName = "amf"
 
-	// AMF image names
-	amfImageName = "registry.opennetworking.org/docker.io/omecproject/5gc-amf:rel-1.6.4"
-
-	// AMF service names
+	// AMF config and service names
+	amfConfigName  = "amf-config"
 	amfServiceName = "amf-service"
 
 	// AMF port names
@@ -41,14 +35,28 @@ const (
 	amfPromPort     = 9089
 )
 
-// reconcileConfigMap reconciles the ConfigMap for the AMF
-func reconcileConfigMap(ctx context.Context, c client.Client, nfDeployment *nephiov1alpha1.NFDeployment) (bool, error) {
-	log := log.FromContext(ctx).WithValues("AMFConfigMap", nfDeployment.Name)
+// buildConfigMap builds the ConfigMap holding the AMF configuration and run script
+func buildConfigMap(nfDeployment *nephiov1alpha1.NFDeployment, params *controllers.Parameters) (*apiv1.ConfigMap, error) {
+	identity, err := params.NetworkIdentity()
+	if err != nil {
+		return nil, err
+	}
+	// The AMF serves NGAP on the N2 addresses, it cannot run without one
+	n2Addresses, err := controllers.RequiredInterfaceIPs(nfDeployment, "n2")
+	if err != nil {
+		return nil, err
+	}
+
+	bindAddress := controllers.SBIBindAddress(controllers.GetIPFamilies(nfDeployment, params))
+	amfConfig, err := controllers.MergeYAMLConfig(generateAMFConfig(nfDeployment, bindAddress, n2Addresses, identity),
+		params.GetOverrides(controllers.NFTypeAMF))
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge AMF configuration overrides: %w", err)
+	}
 
-	configMapName := controllers.GetNamespacedName(nfDeployment, "amf-config")
-	configMap := &apiv1.ConfigMap{
+	return &apiv1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      configMapName,
+			Name:      controllers.GetNamespacedName(nfDeployment, amfConfigName),
 			Namespace: nfDeployment.Namespace,
 			Labels: map[string]string{
 				"app": controllers.GetNamespacedName(nfDeployment, "amf"),
@@ -56,53 +64,15 @@ func reconcileConfigMap(ctx context.Context, c client.Client, nfDeployment *neph
 		},
 		Data: map[string]string{
 			"amf-run.sh":  generateAMFRunScript(),
-			"amfcfg.yaml": generateAMFConfig(nfDeployment),
+			"amfcfg.yaml": amfConfig,
 		},
-	}
-
-	// Set the owner reference
-	if err := controllerutil.SetControllerReference(nfDeployment, configMap, c.Scheme()); err != nil {
-		log.Error(err, "Failed to set owner reference on ConfigMap")
-		return false, err
-	}
-
-	// Check if the ConfigMap already exists
-	existingConfigMap := &apiv1.ConfigMap{}
-	err := c.Get(ctx, client.ObjectKey{Namespace: nfDeployment.Namespace, Name: configMapName}, existingConfigMap)
-	if err != nil {
-		if k8serrors.IsNotFound(err) {
-			// Create the ConfigMap
-			log.Info("Creating ConfigMap", "ConfigMap.Name", configMapName)
-			if err := c.Create(ctx, configMap); err != nil {
-				log.Error(err, "Failed to create ConfigMap")
-				return false, err
-			}
-			return true, nil
-		}
-		log.Error(err, "Failed to get ConfigMap")
-		return false, err
-	}
-
-	// Update the ConfigMap if needed
-	if !mapsEqual(existingConfigMap.Data, configMap.Data) {
-		log.Info("Updating ConfigMap", "ConfigMap.Name", configMapName)
-		existingConfigMap.Data = configMap.Data
-		if err := c.Update(ctx, existingConfigMap); err != nil {
-			log.Error(err, "Failed to update ConfigMap")
-			return false, err
-		}
-		return true, nil
-	}
-
-	return false, nil
+	}, nil
 }
 
-// reconcileDeployment reconciles the Deployment for the AMF
-func reconcileDeployment(ctx context.Context, c client.Client, scheme *runtime.Scheme, nfDeployment *nephiov1alpha1.NFDeployment) (bool, error) {
-	log := log.FromContext(ctx).WithValues("AMFDeployment", nfDeployment.Name)
-
+// buildDeployment builds the Deployment for the AMF
+func buildDeployment(nfDeployment *nephiov1alpha1.NFDeployment, params *controllers.Parameters) *appsv1.Deployment {
 	deploymentName := controllers.GetNamespacedName(nfDeployment, "amf")
-	deployment := &appsv1.Deployment{
+	return &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      deploymentName,
 			Namespace: nfDeployment.Namespace,
@@ -127,7 +97,7 @@ func reconcileDeployment(ctx context.Context, c client.Client, scheme *runtime.S
 					Containers: []apiv1.Container{
 						{
 							Name:  amfContainerName,
-							Image: amfImageName,
+							Image: params.GetImage(controllers.ImageAMF),
 							Ports: []apiv1.ContainerPort{
 								{
 									Name:          amfNgappPortName,
@@ -197,7 +167,7 @@ func reconcileDeployment(ctx context.Context, c client.Client, scheme *runtime.S
 							VolumeSource: apiv1.VolumeSource{
 								ConfigMap: &apiv1.ConfigMapVolumeSource{
 									LocalObjectReference: apiv1.LocalObjectReference{
-										Name: controllers.GetNamespacedName(nfDeployment, "amf-config"),
+										Name: controllers.GetNamespacedName(nfDeployment, amfConfigName),
 									},
 									DefaultMode: int32Ptr(0755), // Executable permission for scripts
 								},
@@ -208,49 +178,11 @@ func reconcileDeployment(ctx context.Context, c client.Client, scheme *runtime.S
 			},
 		},
 	}
-
-	// Set the owner reference
-	if err := controllerutil.SetControllerReference(nfDeployment, deployment, scheme); err != nil {
-		log.Error(err, "Failed to set owner reference on Deployment")
-		return false, err
-	}
-
-	// Check if the Deployment already exists
-	existingDeployment := &appsv1.Deployment{}
-	err := c.Get(ctx, client.ObjectKey{Namespace: nfDeployment.Namespace, Name: deploymentName}, existingDeployment)
-	if err != nil {
-		if k8serrors.IsNotFound(err) {
-			// Create the Deployment
-			log.Info("Creating Deployment", "Deployment.Name", deploymentName)
-			if err := c.Create(ctx, deployment); err != nil {
-				log.Error(err, "Failed to create Deployment")
-				return false, err
-			}
-			return true, nil
-		}
-		log.Error(err, "Failed to get Deployment")
-		return false, err
-	}
-
-	// Update the Deployment if needed
-	if !deploymentEqual(existingDeployment, deployment) {
-		log.Info("Updating Deployment", "Deployment.Name", deploymentName)
-		existingDeployment.Spec = deployment.Spec
-		if err := c.Update(ctx, existingDeployment); err != nil {
-			log.Error(err, "Failed to update Deployment")
-			return false, err
-		}
-		return true, nil
-	}
-
-	return false, nil
 }
 
-// reconcileService reconciles the Service for the AMF
-func reconcileService(ctx context.Context, c client.Client, scheme *runtime.Scheme, nfDeployment *nephiov1alpha1.NFDeployment) (bool, error) {
-	log := log.FromContext(ctx).WithValues("AMFService", nfDeployment.Name)
-
-	serviceName := controllers.GetNamespacedName(nfDeployment, "amf-service")
+// buildServices builds the AMF Service and the headless Service used for service discovery
+func buildServices(nfDeployment *nephiov1alpha1.NFDeployment) []*apiv1.Service {
+	serviceName := controllers.GetNamespacedName(nfDeployment, amfServiceName)
 	service := &apiv1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      serviceName,
@@ -317,80 +249,7 @@ func reconcileService(ctx context.Context, c client.Client, scheme *runtime.Sche
 		},
 	}
 
-	// Set the owner reference for main service
-	if err := controllerutil.SetControllerReference(nfDeployment, service, scheme); err != nil {
-		log.Error(err, "Failed to set owner reference on Service")
-		return false, err
-	}
-
-	// Set the owner reference for headless service
-	if err := controllerutil.SetControllerReference(nfDeployment, headlessService, scheme); err != nil {
-		log.Error(err, "Failed to set owner reference on Headless Service")
-		return false, err
-	}
-
-	// Check if the main Service already exists
-	existingService := &apiv1.Service{}
-	err := c.Get(ctx, client.ObjectKey{Namespace: nfDeployment.Namespace, Name: serviceName}, existingService)
-	servicesChanged := false
-	if err != nil {
-		if k8serrors.IsNotFound(err) {
-			// Create the Service
-			log.Info("Creating Service", "Service.Name", serviceName)
-			if err := c.Create(ctx, service); err != nil {
-				log.Error(err, "Failed to create Service")
-				return false, err
-			}
-			servicesChanged = true
-		} else {
-			log.Error(err, "Failed to get Service")
-			return false, err
-		}
-	} else {
-		// Update the Service if needed
-		if !serviceEqual(existingService, service) {
-			log.Info("Updating Service", "Service.Name", serviceName)
-			// Preserve the ClusterIP
-			service.Spec.ClusterIP = existingService.Spec.ClusterIP
-			existingService.Spec = service.Spec
-			if err := c.Update(ctx, existingService); err != nil {
-				log.Error(err, "Failed to update Service")
-				return false, err
-			}
-			servicesChanged = true
-		}
-	}
-
-	// Check if the headless Service already exists
-	existingHeadlessService := &apiv1.Service{}
-	err = c.Get(ctx, client.ObjectKey{Namespace: nfDeployment.Namespace, Name: headlessServiceName}, existingHeadlessService)
-	if err != nil {
-		if k8serrors.IsNotFound(err) {
-			// Create the headless Service
-			log.Info("Creating Headless Service", "Service.Name", headlessServiceName)
-			if err := c.Create(ctx, headlessService); err != nil {
-				log.Error(err, "Failed to create Headless Service")
-				return false, err
-			}
-			servicesChanged = true
-		} else {
-			log.Error(err, "Failed to get Headless Service")
-			return false, err
-		}
-	} else {
-		// Update the headless Service if needed
-		if !serviceEqual(existingHeadlessService, headlessService) {
-			log.Info("Updating Headless Service", "Service.Name", headlessServiceName)
-			existingHeadlessService.Spec = headlessService.Spec
-			if err := c.Update(ctx, existingHeadlessService); err != nil {
-				log.Error(err, "Failed to update Headless Service")
-				return false, err
-			}
-			servicesChanged = true
-		}
-	}
-
-	return servicesChanged, nil
+	return []*apiv1.Service{service, headlessService}
 }
 
 // Helper functions
@@ -408,31 +267,6 @@ func createResourceList(cpu, memory string) apiv1.ResourceList {
 	}
 }
 
-// mapsEqual compares two maps for equality
-func mapsEqual(a, b map[string]string) bool {
-	if len(a) != len(b) {
-		return false
-	}
-	for k, v := range a {
-		if bv, ok := b[k]; !ok || bv != v {
-			return false
-		}
-	}
-	return true
-}
-
-// deploymentEqual compares two deployments for equality
-func deploymentEqual(a, b *appsv1.Deployment) bool {
-	// Simple check for now
-	return a.Spec.Template.Spec.Containers[0].Image == b.Spec.Template.Spec.Containers[0].Image
-}
-
-// serviceEqual compares two services for equality
-func serviceEqual(a, b *apiv1.Service) bool {
-	// Simple check for now
-	return len(a.Spec.Ports) == len(b.Spec.Ports)
-}
-
 // generateAMFRunScript generates the AMF run script
 func generateAMFRunScript() string {
 	return `#!/bin/bash
@@ -442,23 +276,11 @@ cd /free5gc
 }
 
 // generateAMFConfig generates the AMF configuration
-func generateAMFConfig(nfDeployment *nephiov1alpha1.NFDeployment) string {
-	// Get the N2 address from the NFDeployment
-	var n2Address string
-	for _, iface := range nfDeployment.Spec.Interfaces {
-		if iface.Name == "n2" && iface.IPv4 != nil {
-			n2Address = iface.IPv4.Address
-			break
-		}
-	}
-
-	// Extract the IP address without the CIDR
-	if n2Address != "" {
-		n2Address = n2Address[:len(n2Address)-3]
-	} else {
-		// Default address if not specified
-		n2Address = "192.168.251.5"
-	}
+func generateAMFConfig(nfDeployment *nephiov1alpha1.NFDeployment, bindAddress string, n2Addresses []netip.Addr,
+	identity controllers.NetworkIdentity) string {
+	// Register the AMF Service name rather than an N2 address with the NRF, SBI peers reach the AMF
+	// over the pod network
+	serviceName := controllers.GetNamespacedName(nfDeployment, amfServiceName)
 
 	return fmt.Sprintf(`info:
   version: 1.0.0
@@ -467,11 +289,10 @@ func generateAMFConfig(nfDeployment *nephiov1alpha1.NFDeployment) string {
 configuration:
   amfName: AMF
   ngapIpList:
-    - %s
-  sbi:
+%s  sbi:
     scheme: http
     registerIPv4: %s
-    bindingIPv4: 0.0.0.0
+    bindingIPv4: "%s"
     port: 8080
   serviceNameList:
     - namf-comm
@@ -480,27 +301,10 @@ configuration:
     - namf-loc
     - namf-oam
   servedGuamiList:
-    - plmnId:
-        mcc: 208
-        mnc: 93
-      amfId: cafe00
-  supportTaiList:
-    - plmnId:
-        mcc: 208
-        mnc: 93
-      tac: 1
-  plmnSupportList:
-    - plmnId:
-        mcc: 208
-        mnc: 93
-      snssaiList:
-        - sst: 1
-          sd: 010203
-        - sst: 1
-          sd: 112233
-  supportDnnList:
-    - internet
-  nrfUri: http://nrf-service:8000
+%s  supportTaiList:
+%s  plmnSupportList:
+%s  supportDnnList:
+%s  nrfUri: %s
   security:
     integrityOrder:
       - NIA2
@@ -515,5 +319,64 @@ configuration:
   t3502: 720
   t3512: 3600
   non3gppDeregistrationTimer: 3240
-`, n2Address, n2Address)
+`, generateNgapIPList(n2Addresses), serviceName, bindAddress, generateGuamiList(identity.GUAMIs), generateTaiList(identity.TAIs),
+		generatePlmnSupportList(identity.PLMNs, identity.Slices), generateDnnList(identity.DNNs()),
+		controllers.GetNRFURI(nfDeployment))
+}
+
+// generateNgapIPList renders the addresses NGAP listens on, both of a dual-stack N2 interface
+func generateNgapIPList(addresses []netip.Addr) string {
+	var b strings.Builder
+	for _, address := range addresses {
+		fmt.Fprintf(&b, "    - %s\n", address)
+	}
+	return b.String()
+}
+
+// generatePlmnID renders a plmnId whose fields are at the given indentation, MCC and MNC are quoted
+// to keep their leading zeros
+func generatePlmnID(plmn controllers.PLMN, indent string) string {
+	return fmt.Sprintf("plmnId:\n%smcc: \"%s\"\n%smnc: \"%s\"\n", indent, plmn.MCC, indent, plmn.MNC)
+}
+
+// generateGuamiList renders the GUAMIs served by the AMF
+func generateGuamiList(guamis []controllers.GUAMI) string {
+	var b strings.Builder
+	for _, guami := range guamis {
+		b.WriteString("    - " + generatePlmnID(guami.PLMN, "        "))
+		fmt.Fprintf(&b, "      amfId: %s\n", guami.AMFID)
+	}
+	return b.String()
+}
+
+// generateTaiList renders the tracking areas supported by the AMF
+func generateTaiList(tais []controllers.TAI) string {
+	var b strings.Builder
+	for _, tai := range tais {
+		b.WriteString("    - " + generatePlmnID(tai.PLMN, "        "))
+		fmt.Fprintf(&b, "      tac: %d\n", tai.TAC)
+	}
+	return b.String()
+}
+
+// generatePlmnSupportList renders the S-NSSAIs supported in each PLMN
+func generatePlmnSupportList(plmns []controllers.PLMN, slices []controllers.Slice) string {
+	var b strings.Builder
+	for _, plmn := range plmns {
+		b.WriteString("    - " + generatePlmnID(plmn, "        "))
+		b.WriteString("      snssaiList:\n")
+		for _, slice := range slices {
+			fmt.Fprintf(&b, "        - sst: %d\n          sd: \"%s\"\n", slice.SST, slice.SD)
+		}
+	}
+	return b.String()
+}
+
+// generateDnnList renders the DNNs reachable on any of the slices
+func generateDnnList(dnns []string) string {
+	var b strings.Builder
+	for _, dnn := range dnns {
+		fmt.Fprintf(&b, "    - %s\n", dnn)
+	}
+	return b.String()
 }
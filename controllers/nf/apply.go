@@ -0,0 +1,216 @@
+package nf
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/RohitRathore1/sdcore-operator/controllers"
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/csaupgrade"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// FieldManager is the field manager the resources of network functions are applied with
+	FieldManager = "sdcore-operator"
+
+	// legacyFieldManager owns the fields of resources created by update, before server-side apply was used.
+	// It is the name of the operator binary.
+	legacyFieldManager = "manager"
+
+	// conflictPollInterval is the interval resources controlled by another owner are retried at, e.g. until
+	// the NFDeployment owning a shared Service is deleted
+	conflictPollInterval = time.Minute
+)
+
+// controlledByOtherError reports a resource the NFDeployment cannot apply because another owner, e.g. another
+// NRF NFDeployment owning the well-known nrf-service Service, controls it
+type controlledByOtherError struct {
+	kind, name string
+	owner      metav1.OwnerReference
+}
+
+func (e *controlledByOtherError) Error() string {
+	return fmt.Sprintf("%s %s is controlled by %s %s", e.kind, e.name, e.owner.Kind, e.owner.Name)
+}
+
+// applyResource applies a resource owned by the NFDeployment with server-side apply. Every field set on the
+// desired object is owned by the operator and reverted on drift, fields set by other managers are left alone.
+func (r *NFDeploymentReconciler) applyResource(ctx context.Context, nfDeployment *nephiov1alpha1.NFDeployment, desired client.Object) (controllerutil.OperationResult, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	// Apply requests carry the apiVersion and kind, typed objects built by the NFs leave them empty
+	gvk, err := apiutil.GVKForObject(desired, r.Scheme)
+	if err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+	desired.GetObjectKind().SetGroupVersionKind(gvk)
+	// Set the owner reference so the resource is automatically cleaned up
+	if err := ctrl.SetControllerReference(nfDeployment, desired, r.Scheme); err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+
+	existing, ok := desired.DeepCopyObject().(client.Object)
+	if !ok {
+		return controllerutil.OperationResultNone, fmt.Errorf("unsupported resource type %T", desired)
+	}
+	if err := r.Client.Get(ctx, client.ObjectKeyFromObject(desired), existing); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return controllerutil.OperationResultNone, err
+		}
+		existing = nil
+	}
+
+	if existing != nil {
+		// An object has a single controller, applying a second controller reference is rejected
+		if owner := metav1.GetControllerOf(existing); owner != nil && owner.UID != nfDeployment.UID {
+			return controllerutil.OperationResultNone, &controlledByOtherError{kind: gvk.Kind, name: desired.GetName(), owner: *owner}
+		}
+		if err := r.upgradeManagedFields(ctx, existing); err != nil {
+			return controllerutil.OperationResultNone, err
+		}
+		// Replicas scaled by another controller, e.g. an HPA, are not reset
+		if deployment, ok := desired.(*appsv1.Deployment); ok && managedByOthers(existing, "spec", "replicas") {
+			deployment.Spec.Replicas = nil
+		}
+	}
+
+	if err := r.Client.Patch(ctx, desired, client.Apply, client.FieldOwner(FieldManager), client.ForceOwnership); err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+
+	op := controllerutil.OperationResultNone
+	switch {
+	case existing == nil:
+		op = controllerutil.OperationResultCreated
+	case existing.GetResourceVersion() != desired.GetResourceVersion():
+		op = controllerutil.OperationResultUpdated
+	}
+	log.Info("Resource reconciled", "kind", resourceKind(desired), "name", desired.GetName(), "operation", op)
+	return op, nil
+}
+
+// pruneResources deletes the resources controlled by the NFDeployment that are no longer among the desired
+// objects, e.g. the NetworkAttachmentDefinition of a removed interface. Server-side apply only prunes fields,
+// objects that are no longer rendered would otherwise be left behind until the NFDeployment is deleted.
+func (r *NFDeploymentReconciler) pruneResources(ctx context.Context, nfDeployment *nephiov1alpha1.NFDeployment,
+	desired []client.Object) (bool, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	keep := map[schema.GroupVersionKind]map[string]bool{}
+	for _, object := range desired {
+		gvk, err := apiutil.GVKForObject(object, r.Scheme)
+		if err != nil {
+			return false, err
+		}
+		if keep[gvk] == nil {
+			keep[gvk] = map[string]bool{}
+		}
+		keep[gvk][object.GetName()] = true
+	}
+
+	lists := []client.ObjectList{new(appsv1.DeploymentList), new(apiv1.ConfigMapList), new(apiv1.ServiceList)}
+	// NetworkAttachmentDefinitions can only be listed when Multus is installed
+	gvk := controllers.NetworkAttachmentDefinitionGVK
+	_, err := r.Client.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	switch {
+	case err == nil:
+		nads := new(unstructured.UnstructuredList)
+		nads.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		lists = append(lists, nads)
+	case !meta.IsNoMatchError(err):
+		return false, err
+	}
+
+	pruned := false
+	for _, list := range lists {
+		if err := r.Client.List(ctx, list, client.InNamespace(nfDeployment.Namespace)); err != nil {
+			return false, err
+		}
+		objects, err := meta.ExtractList(list)
+		if err != nil {
+			return false, err
+		}
+		for _, item := range objects {
+			object, ok := item.(client.Object)
+			if !ok {
+				continue
+			}
+			owner := metav1.GetControllerOf(object)
+			if owner == nil || owner.UID != nfDeployment.UID || object.GetDeletionTimestamp() != nil {
+				continue
+			}
+			gvk, err := apiutil.GVKForObject(object, r.Scheme)
+			if err != nil {
+				return false, err
+			}
+			if keep[gvk][object.GetName()] {
+				continue
+			}
+			if err := r.Client.Delete(ctx, object); err != nil && !k8serrors.IsNotFound(err) {
+				return false, err
+			}
+			log.Info("Resource pruned", "kind", gvk.Kind, "name", object.GetName())
+			pruned = true
+		}
+	}
+	return pruned, nil
+}
+
+// upgradeManagedFields hands the fields set by update before server-side apply was used over to the
+// operator field manager, so that fields no longer rendered are removed by the next apply
+func (r *NFDeploymentReconciler) upgradeManagedFields(ctx context.Context, existing client.Object) error {
+	patch, err := csaupgrade.UpgradeManagedFieldsPatch(existing, sets.New(legacyFieldManager), FieldManager)
+	if err != nil || patch == nil {
+		return err
+	}
+	return r.Client.Patch(ctx, existing, client.RawPatch(types.JSONPatchType, patch))
+}
+
+// managedByOthers returns true if a field manager other than the operator owns the field at the path
+func managedByOthers(object client.Object, path ...string) bool {
+	for _, entry := range object.GetManagedFields() {
+		if entry.Manager == FieldManager || entry.FieldsV1 == nil {
+			continue
+		}
+		fields := map[string]interface{}{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+		owned := true
+		for _, name := range path {
+			next, ok := fields["f:"+name].(map[string]interface{})
+			if !ok {
+				owned = false
+				break
+			}
+			fields = next
+		}
+		if owned {
+			return true
+		}
+	}
+	return false
+}
+
+// resourceKind returns the kind of an object for logging
+func resourceKind(object client.Object) string {
+	if u, ok := object.(*unstructured.Unstructured); ok {
+		return u.GetKind()
+	}
+	return fmt.Sprintf("%T", object)
+}
@@ -0,0 +1,63 @@
+package nf
+
+import (
+	"context"
+	"testing"
+
+	"github.com/RohitRathore1/sdcore-operator/controllers"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// TestPruneRemovedInterface checks the NetworkAttachmentDefinition of an interface removed from the
+// NFDeployment is deleted while the other resources are kept
+func TestPruneRemovedInterface(t *testing.T) {
+	ctx := context.Background()
+	nf, ok := controllers.LookupNetworkFunction(controllers.NFTypeUPF)
+	if !ok {
+		t.Fatal("UPF not registered")
+	}
+	params := &controllers.Parameters{}
+	params.SetImageDefaults(controllers.ImageSettings{})
+
+	upf := testNFDeployment("upf", "upf.sdcore.io", "n3=192.168.252.3/24", "n4=192.168.250.3/24",
+		"n6=192.168.249.3/24")
+	applied, err := buildResources(nf, upf, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newTestReconciler(t, upf)
+	for _, object := range applied {
+		if err := ctrl.SetControllerReference(upf, object, r.Scheme); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Client.Create(ctx, object); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	upf.Spec.Interfaces = upf.Spec.Interfaces[:2]
+	desired, err := buildResources(nf, upf, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pruned, err := r.pruneResources(ctx, upf, desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pruned {
+		t.Error("no resource pruned")
+	}
+
+	removed := controllers.NetworkAttachmentName(upf, "n6")
+	for _, object := range applied {
+		err := r.Client.Get(ctx, client.ObjectKeyFromObject(object), object)
+		switch {
+		case object.GetName() == removed && !k8serrors.IsNotFound(err):
+			t.Errorf("attachment %s of the removed interface not pruned: %v", removed, err)
+		case object.GetName() != removed && err != nil:
+			t.Errorf("%s %s: %v", resourceKind(object), object.GetName(), err)
+		}
+	}
+}
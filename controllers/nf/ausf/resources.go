@@ -0,0 +1,249 @@
+package ausf
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/RohitRathore1/sdcore-operator/controllers"
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// AUSF container names
+	ausfContainerName = "ausf"
+
+	// AUSF config and service names
+	ausfConfigName  = "ausf-config"
+	ausfServiceName = "ausf-service"
+
+	// AUSF port names
+	ausfSbiPortName  = "sbi"
+	ausfPromPortName = "prometheus"
+
+	// AUSF port numbers
+	ausfSbiPort  = 8080
+	ausfPromPort = 9089
+)
+
+// buildConfigMap builds the ConfigMap holding the AUSF configuration and run script
+func buildConfigMap(nfDeployment *nephiov1alpha1.NFDeployment, params *controllers.Parameters) (*apiv1.ConfigMap, error) {
+	identity, err := params.NetworkIdentity()
+	if err != nil {
+		return nil, err
+	}
+
+	bindAddress := controllers.SBIBindAddress(controllers.GetIPFamilies(nfDeployment, params))
+	ausfConfig, err := controllers.MergeYAMLConfig(generateAUSFConfig(nfDeployment, bindAddress, identity),
+		params.GetOverrides(controllers.NFTypeAUSF))
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge AUSF configuration overrides: %w", err)
+	}
+
+	return &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      controllers.GetNamespacedName(nfDeployment, ausfConfigName),
+			Namespace: nfDeployment.Namespace,
+			Labels: map[string]string{
+				"app": controllers.GetNamespacedName(nfDeployment, "ausf"),
+			},
+		},
+		Data: map[string]string{
+			"ausf-run.sh":  generateAUSFRunScript(),
+			"ausfcfg.yaml": ausfConfig,
+		},
+	}, nil
+}
+
+// buildDeployment builds the Deployment for the AUSF
+func buildDeployment(nfDeployment *nephiov1alpha1.NFDeployment, params *controllers.Parameters) *appsv1.Deployment {
+	deploymentName := controllers.GetNamespacedName(nfDeployment, "ausf")
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      deploymentName,
+			Namespace: nfDeployment.Namespace,
+			Labels: map[string]string{
+				"app": deploymentName,
+			},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: int32Ptr(1),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": deploymentName,
+				},
+			},
+			Template: apiv1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app": deploymentName,
+					},
+				},
+				Spec: apiv1.PodSpec{
+					Containers: []apiv1.Container{
+						{
+							Name:  ausfContainerName,
+							Image: params.GetImage(controllers.ImageAUSF),
+							Ports: []apiv1.ContainerPort{
+								{
+									Name:          ausfSbiPortName,
+									ContainerPort: ausfSbiPort,
+									Protocol:      apiv1.ProtocolTCP,
+								},
+								{
+									Name:          ausfPromPortName,
+									ContainerPort: ausfPromPort,
+									Protocol:      apiv1.ProtocolTCP,
+								},
+							},
+							Command: []string{
+								"/opt/ausf-run.sh",
+							},
+							VolumeMounts: []apiv1.VolumeMount{
+								{
+									Name:      "ausf-config",
+									MountPath: "/opt",
+								},
+							},
+							Env: []apiv1.EnvVar{
+								{
+									Name:  "GRPC_GO_LOG_VERBOSITY_LEVEL",
+									Value: "99",
+								},
+								{
+									Name:  "GRPC_GO_LOG_SEVERITY_LEVEL",
+									Value: "info",
+								},
+								{
+									Name: "POD_IP",
+									ValueFrom: &apiv1.EnvVarSource{
+										FieldRef: &apiv1.ObjectFieldSelector{
+											FieldPath: "status.podIP",
+										},
+									},
+								},
+							},
+							Resources: apiv1.ResourceRequirements{
+								Requests: createResourceList("500m", "512Mi"),
+								Limits:   createResourceList("500m", "512Mi"),
+							},
+							ReadinessProbe: &apiv1.Probe{
+								ProbeHandler: apiv1.ProbeHandler{
+									TCPSocket: &apiv1.TCPSocketAction{
+										Port: intstr.FromInt(ausfSbiPort),
+									},
+								},
+								InitialDelaySeconds: 5,
+								PeriodSeconds:       10,
+							},
+						},
+					},
+					Volumes: []apiv1.Volume{
+						{
+							Name: "ausf-config",
+							VolumeSource: apiv1.VolumeSource{
+								ConfigMap: &apiv1.ConfigMapVolumeSource{
+									LocalObjectReference: apiv1.LocalObjectReference{
+										Name: controllers.GetNamespacedName(nfDeployment, ausfConfigName),
+									},
+									DefaultMode: int32Ptr(0755), // Executable permission for scripts
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// buildService builds the Service exposing the AUSF SBI endpoint
+func buildService(nfDeployment *nephiov1alpha1.NFDeployment) *apiv1.Service {
+	selector := map[string]string{
+		"app": controllers.GetNamespacedName(nfDeployment, "ausf"),
+	}
+
+	return &apiv1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      controllers.GetNamespacedName(nfDeployment, ausfServiceName),
+			Namespace: nfDeployment.Namespace,
+			Labels:    selector,
+		},
+		Spec: apiv1.ServiceSpec{
+			Selector: selector,
+			Ports: []apiv1.ServicePort{
+				{
+					Name:       ausfSbiPortName,
+					Port:       ausfSbiPort,
+					TargetPort: intstr.FromInt(ausfSbiPort),
+					Protocol:   apiv1.ProtocolTCP,
+				},
+				{
+					Name:       ausfPromPortName,
+					Port:       ausfPromPort,
+					TargetPort: intstr.FromInt(ausfPromPort),
+					Protocol:   apiv1.ProtocolTCP,
+				},
+			},
+		},
+	}
+}
+
+// Helper functions
+
+// int32Ptr returns a pointer to an int32
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+// createResourceList creates a resource list from CPU and memory values
+func createResourceList(cpu, memory string) apiv1.ResourceList {
+	return apiv1.ResourceList{
+		apiv1.ResourceCPU:    resource.MustParse(cpu),
+		apiv1.ResourceMemory: resource.MustParse(memory),
+	}
+}
+
+// generateAUSFRunScript generates the AUSF run script
+func generateAUSFRunScript() string {
+	return `#!/bin/bash
+cd /free5gc
+./bin/ausf -c /opt/ausfcfg.yaml
+`
+}
+
+// generateAUSFConfig generates the AUSF configuration
+func generateAUSFConfig(nfDeployment *nephiov1alpha1.NFDeployment, bindAddress string, identity controllers.NetworkIdentity) string {
+	// Register the AUSF Service name rather than the pod IP with the NRF
+	serviceName := controllers.GetNamespacedName(nfDeployment, ausfServiceName)
+
+	return fmt.Sprintf(`info:
+  version: 1.0.0
+  description: AUSF initial configuration
+
+configuration:
+  sbi:
+    scheme: http
+    registerIPv4: %s
+    bindingIPv4: "%s"
+    port: %d
+  serviceNameList:
+    - nausf-auth
+  nrfUri: %s
+  plmnSupportList:
+%s  groupId: ausfGroup001
+`, serviceName, bindAddress, ausfSbiPort, controllers.GetNRFURI(nfDeployment), generatePlmnSupportList(identity.PLMNs))
+}
+
+// generatePlmnSupportList renders the PLMNs the AUSF authenticates subscribers of
+func generatePlmnSupportList(plmns []controllers.PLMN) string {
+	var b strings.Builder
+	for _, plmn := range plmns {
+		fmt.Fprintf(&b, "    - mcc: \"%s\"\n      mnc: \"%s\"\n", plmn.MCC, plmn.MNC)
+	}
+	return b.String()
+}
@@ -0,0 +1,15 @@
+package nf
+
+// Each SD-Core NF package registers its controllers.NetworkFunction at init,
+// adding a network function only requires importing its package here.
+import (
+	_ "github.com/RohitRathore1/sdcore-operator/controllers/nf/amf"
+	_ "github.com/RohitRathore1/sdcore-operator/controllers/nf/ausf"
+	_ "github.com/RohitRathore1/sdcore-operator/controllers/nf/nrf"
+	_ "github.com/RohitRathore1/sdcore-operator/controllers/nf/nssf"
+	_ "github.com/RohitRathore1/sdcore-operator/controllers/nf/pcf"
+	_ "github.com/RohitRathore1/sdcore-operator/controllers/nf/smf"
+	_ "github.com/RohitRathore1/sdcore-operator/controllers/nf/udm"
+	_ "github.com/RohitRathore1/sdcore-operator/controllers/nf/udr"
+	_ "github.com/RohitRathore1/sdcore-operator/controllers/nf/upf"
+)
@@ -0,0 +1,41 @@
+package nrf
+
+import (
+	"github.com/RohitRathore1/sdcore-operator/controllers"
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func init() {
+	controllers.Register(networkFunction{})
+}
+
+// networkFunction implements controllers.NetworkFunction for the NRF
+type networkFunction struct{}
+
+// Type returns the NRF NF type
+func (networkFunction) Type() controllers.NFType {
+	return controllers.NFTypeNRF
+}
+
+// MatchesProvider returns true for the nrf.sdcore.io provider
+func (networkFunction) MatchesProvider(provider string) bool {
+	return controllers.MatchesSDCoreProvider(controllers.NFTypeNRF, provider)
+}
+
+// BuildResources returns the ConfigMap, Deployment and Services of the NRF
+func (networkFunction) BuildResources(nfDeployment *nephiov1alpha1.NFDeployment, params *controllers.Parameters) ([]client.Object, error) {
+	configMap, err := buildConfigMap(nfDeployment, params)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := []client.Object{
+		configMap,
+		buildDeployment(nfDeployment, params),
+	}
+	for _, service := range buildServices(nfDeployment) {
+		objects = append(objects, service)
+	}
+	return objects, nil
+}
@@ -0,0 +1,270 @@
+package nrf
+
+import (
+	"fmt"
+
+	"github.com/RohitRathore1/sdcore-operator/controllers"
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// NRF container names
+	nrfContainerName = "nrf"
+
+	// NRF config and service names
+	nrfConfigName  = "nrf-config"
+	nrfServiceName = "nrf-service"
+
+	// NRF port names
+	nrfSbiPortName  = "sbi"
+	nrfPromPortName = "prometheus"
+
+	// NRF port numbers
+	nrfSbiPort  = 8080
+	nrfPromPort = 9089
+)
+
+// buildConfigMap builds the ConfigMap holding the NRF configuration and run script
+func buildConfigMap(nfDeployment *nephiov1alpha1.NFDeployment, params *controllers.Parameters) (*apiv1.ConfigMap, error) {
+	identity, err := params.NetworkIdentity()
+	if err != nil {
+		return nil, err
+	}
+
+	bindAddress := controllers.SBIBindAddress(controllers.GetIPFamilies(nfDeployment, params))
+	nrfConfig, err := controllers.MergeYAMLConfig(generateNRFConfig(nfDeployment, bindAddress, identity),
+		params.GetOverrides(controllers.NFTypeNRF))
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge NRF configuration overrides: %w", err)
+	}
+
+	return &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      controllers.GetNamespacedName(nfDeployment, nrfConfigName),
+			Namespace: nfDeployment.Namespace,
+			Labels: map[string]string{
+				"app": controllers.GetNamespacedName(nfDeployment, "nrf"),
+			},
+		},
+		Data: map[string]string{
+			"nrf-run.sh":  generateNRFRunScript(),
+			"nrfcfg.yaml": nrfConfig,
+		},
+	}, nil
+}
+
+// buildDeployment builds the Deployment for the NRF
+func buildDeployment(nfDeployment *nephiov1alpha1.NFDeployment, params *controllers.Parameters) *appsv1.Deployment {
+	deploymentName := controllers.GetNamespacedName(nfDeployment, "nrf")
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      deploymentName,
+			Namespace: nfDeployment.Namespace,
+			Labels: map[string]string{
+				"app": deploymentName,
+			},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: int32Ptr(1),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": deploymentName,
+				},
+			},
+			Template: apiv1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app": deploymentName,
+					},
+				},
+				Spec: apiv1.PodSpec{
+					Containers: []apiv1.Container{
+						{
+							Name:  nrfContainerName,
+							Image: params.GetImage(controllers.ImageNRF),
+							Ports: []apiv1.ContainerPort{
+								{
+									Name:          nrfSbiPortName,
+									ContainerPort: nrfSbiPort,
+									Protocol:      apiv1.ProtocolTCP,
+								},
+								{
+									Name:          nrfPromPortName,
+									ContainerPort: nrfPromPort,
+									Protocol:      apiv1.ProtocolTCP,
+								},
+							},
+							Command: []string{
+								"/opt/nrf-run.sh",
+							},
+							VolumeMounts: []apiv1.VolumeMount{
+								{
+									Name:      "nrf-config",
+									MountPath: "/opt",
+								},
+							},
+							Env: []apiv1.EnvVar{
+								{
+									Name:  "GRPC_GO_LOG_VERBOSITY_LEVEL",
+									Value: "99",
+								},
+								{
+									Name:  "GRPC_GO_LOG_SEVERITY_LEVEL",
+									Value: "info",
+								},
+								{
+									Name: "POD_IP",
+									ValueFrom: &apiv1.EnvVarSource{
+										FieldRef: &apiv1.ObjectFieldSelector{
+											FieldPath: "status.podIP",
+										},
+									},
+								},
+							},
+							Resources: apiv1.ResourceRequirements{
+								Requests: createResourceList("500m", "512Mi"),
+								Limits:   createResourceList("500m", "512Mi"),
+							},
+							ReadinessProbe: &apiv1.Probe{
+								ProbeHandler: apiv1.ProbeHandler{
+									TCPSocket: &apiv1.TCPSocketAction{
+										Port: intstr.FromInt(nrfSbiPort),
+									},
+								},
+								InitialDelaySeconds: 5,
+								PeriodSeconds:       10,
+							},
+						},
+					},
+					Volumes: []apiv1.Volume{
+						{
+							Name: "nrf-config",
+							VolumeSource: apiv1.VolumeSource{
+								ConfigMap: &apiv1.ConfigMapVolumeSource{
+									LocalObjectReference: apiv1.LocalObjectReference{
+										Name: controllers.GetNamespacedName(nfDeployment, nrfConfigName),
+									},
+									DefaultMode: int32Ptr(0755), // Executable permission for scripts
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// buildServices builds the NRF Service and the well-known Service the other NFs register with
+func buildServices(nfDeployment *nephiov1alpha1.NFDeployment) []*apiv1.Service {
+	selector := map[string]string{
+		"app": controllers.GetNamespacedName(nfDeployment, "nrf"),
+	}
+
+	service := &apiv1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      controllers.GetNamespacedName(nfDeployment, nrfServiceName),
+			Namespace: nfDeployment.Namespace,
+			Labels:    selector,
+		},
+		Spec: apiv1.ServiceSpec{
+			Selector: selector,
+			Ports: []apiv1.ServicePort{
+				{
+					Name:       nrfSbiPortName,
+					Port:       nrfSbiPort,
+					TargetPort: intstr.FromInt(nrfSbiPort),
+					Protocol:   apiv1.ProtocolTCP,
+				},
+				{
+					Name:       nrfPromPortName,
+					Port:       nrfPromPort,
+					TargetPort: intstr.FromInt(nrfPromPort),
+					Protocol:   apiv1.ProtocolTCP,
+				},
+			},
+		},
+	}
+
+	// The AMF, SMF and other NFs default to http://nrf-service:8000 unless overridden,
+	// so expose the NRF SBI under that well-known name as well
+	wellKnownService := &apiv1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      controllers.NRFServiceName,
+			Namespace: nfDeployment.Namespace,
+			Labels:    selector,
+		},
+		Spec: apiv1.ServiceSpec{
+			Selector: selector,
+			Ports: []apiv1.ServicePort{
+				{
+					Name:       nrfSbiPortName,
+					Port:       controllers.NRFServicePort,
+					TargetPort: intstr.FromInt(nrfSbiPort),
+					Protocol:   apiv1.ProtocolTCP,
+				},
+			},
+		},
+	}
+
+	return []*apiv1.Service{service, wellKnownService}
+}
+
+// Helper functions
+
+// int32Ptr returns a pointer to an int32
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+// createResourceList creates a resource list from CPU and memory values
+func createResourceList(cpu, memory string) apiv1.ResourceList {
+	return apiv1.ResourceList{
+		apiv1.ResourceCPU:    resource.MustParse(cpu),
+		apiv1.ResourceMemory: resource.MustParse(memory),
+	}
+}
+
+// generateNRFRunScript generates the NRF run script
+func generateNRFRunScript() string {
+	return `#!/bin/bash
+cd /free5gc
+./bin/nrf -c /opt/nrfcfg.yaml
+`
+}
+
+// generateNRFConfig generates the NRF configuration
+func generateNRFConfig(nfDeployment *nephiov1alpha1.NFDeployment, bindAddress string, identity controllers.NetworkIdentity) string {
+	// The NRF registers under its Service name so that NF profiles point at a stable address
+	serviceName := controllers.GetNamespacedName(nfDeployment, nrfServiceName)
+	// NF profiles registered without a PLMN are assigned the first served PLMN
+	plmn := identity.PLMNs[0]
+
+	return fmt.Sprintf(`info:
+  version: 1.0.0
+  description: NRF initial configuration
+
+configuration:
+  MongoDBName: %s
+  MongoDBUrl: %s
+  MongoDBStreamEnable: true
+  nfProfileExpiryEnable: true
+  nfKeepAliveTime: 60
+  DefaultPlmnId:
+    mcc: "%s"
+    mnc: "%s"
+  sbi:
+    scheme: http
+    registerIPv4: %s
+    bindingIPv4: "%s"
+    port: %d
+  serviceNameList:
+    - nnrf-nfm
+    - nnrf-disc
+`, controllers.DefaultMongoDBName, controllers.GetMongoDBURL(nfDeployment), plmn.MCC, plmn.MNC, serviceName, bindAddress, nrfSbiPort)
+}
@@ -0,0 +1,300 @@
+package nssf
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/RohitRathore1/sdcore-operator/controllers"
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// NSSF container names
+	nssfContainerName = "nssf"
+
+	// NSSF config and service names
+	nssfConfigName  = "nssf-config"
+	nssfServiceName = "nssf-service"
+
+	// NSSF port names
+	nssfSbiPortName  = "sbi"
+	nssfPromPortName = "prometheus"
+
+	// NSSF port numbers
+	nssfSbiPort  = 8080
+	nssfPromPort = 9089
+)
+
+// buildConfigMap builds the ConfigMap holding the NSSF configuration and run script
+func buildConfigMap(nfDeployment *nephiov1alpha1.NFDeployment, params *controllers.Parameters) (*apiv1.ConfigMap, error) {
+	identity, err := params.NetworkIdentity()
+	if err != nil {
+		return nil, err
+	}
+
+	bindAddress := controllers.SBIBindAddress(controllers.GetIPFamilies(nfDeployment, params))
+	nssfConfig, err := controllers.MergeYAMLConfig(generateNSSFConfig(nfDeployment, bindAddress, identity),
+		params.GetOverrides(controllers.NFTypeNSSF))
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge NSSF configuration overrides: %w", err)
+	}
+
+	return &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      controllers.GetNamespacedName(nfDeployment, nssfConfigName),
+			Namespace: nfDeployment.Namespace,
+			Labels: map[string]string{
+				"app": controllers.GetNamespacedName(nfDeployment, "nssf"),
+			},
+		},
+		Data: map[string]string{
+			"nssf-run.sh":  generateNSSFRunScript(),
+			"nssfcfg.yaml": nssfConfig,
+		},
+	}, nil
+}
+
+// buildDeployment builds the Deployment for the NSSF
+func buildDeployment(nfDeployment *nephiov1alpha1.NFDeployment, params *controllers.Parameters) *appsv1.Deployment {
+	deploymentName := controllers.GetNamespacedName(nfDeployment, "nssf")
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      deploymentName,
+			Namespace: nfDeployment.Namespace,
+			Labels: map[string]string{
+				"app": deploymentName,
+			},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: int32Ptr(1),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": deploymentName,
+				},
+			},
+			Template: apiv1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app": deploymentName,
+					},
+				},
+				Spec: apiv1.PodSpec{
+					Containers: []apiv1.Container{
+						{
+							Name:  nssfContainerName,
+							Image: params.GetImage(controllers.ImageNSSF),
+							Ports: []apiv1.ContainerPort{
+								{
+									Name:          nssfSbiPortName,
+									ContainerPort: nssfSbiPort,
+									Protocol:      apiv1.ProtocolTCP,
+								},
+								{
+									Name:          nssfPromPortName,
+									ContainerPort: nssfPromPort,
+									Protocol:      apiv1.ProtocolTCP,
+								},
+							},
+							Command: []string{
+								"/opt/nssf-run.sh",
+							},
+							VolumeMounts: []apiv1.VolumeMount{
+								{
+									Name:      "nssf-config",
+									MountPath: "/opt",
+								},
+							},
+							Env: []apiv1.EnvVar{
+								{
+									Name:  "GRPC_GO_LOG_VERBOSITY_LEVEL",
+									Value: "99",
+								},
+								{
+									Name:  "GRPC_GO_LOG_SEVERITY_LEVEL",
+									Value: "info",
+								},
+								{
+									Name: "POD_IP",
+									ValueFrom: &apiv1.EnvVarSource{
+										FieldRef: &apiv1.ObjectFieldSelector{
+											FieldPath: "status.podIP",
+										},
+									},
+								},
+							},
+							Resources: apiv1.ResourceRequirements{
+								Requests: createResourceList("500m", "512Mi"),
+								Limits:   createResourceList("500m", "512Mi"),
+							},
+							ReadinessProbe: &apiv1.Probe{
+								ProbeHandler: apiv1.ProbeHandler{
+									TCPSocket: &apiv1.TCPSocketAction{
+										Port: intstr.FromInt(nssfSbiPort),
+									},
+								},
+								InitialDelaySeconds: 5,
+								PeriodSeconds:       10,
+							},
+						},
+					},
+					Volumes: []apiv1.Volume{
+						{
+							Name: "nssf-config",
+							VolumeSource: apiv1.VolumeSource{
+								ConfigMap: &apiv1.ConfigMapVolumeSource{
+									LocalObjectReference: apiv1.LocalObjectReference{
+										Name: controllers.GetNamespacedName(nfDeployment, nssfConfigName),
+									},
+									DefaultMode: int32Ptr(0755), // Executable permission for scripts
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// buildService builds the Service exposing the NSSF SBI endpoint
+func buildService(nfDeployment *nephiov1alpha1.NFDeployment) *apiv1.Service {
+	selector := map[string]string{
+		"app": controllers.GetNamespacedName(nfDeployment, "nssf"),
+	}
+
+	return &apiv1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      controllers.GetNamespacedName(nfDeployment, nssfServiceName),
+			Namespace: nfDeployment.Namespace,
+			Labels:    selector,
+		},
+		Spec: apiv1.ServiceSpec{
+			Selector: selector,
+			Ports: []apiv1.ServicePort{
+				{
+					Name:       nssfSbiPortName,
+					Port:       nssfSbiPort,
+					TargetPort: intstr.FromInt(nssfSbiPort),
+					Protocol:   apiv1.ProtocolTCP,
+				},
+				{
+					Name:       nssfPromPortName,
+					Port:       nssfPromPort,
+					TargetPort: intstr.FromInt(nssfPromPort),
+					Protocol:   apiv1.ProtocolTCP,
+				},
+			},
+		},
+	}
+}
+
+// Helper functions
+
+// int32Ptr returns a pointer to an int32
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+// createResourceList creates a resource list from CPU and memory values
+func createResourceList(cpu, memory string) apiv1.ResourceList {
+	return apiv1.ResourceList{
+		apiv1.ResourceCPU:    resource.MustParse(cpu),
+		apiv1.ResourceMemory: resource.MustParse(memory),
+	}
+}
+
+// generateNSSFRunScript generates the NSSF run script
+func generateNSSFRunScript() string {
+	return `#!/bin/bash
+cd /free5gc
+./bin/nssf -c /opt/nssfcfg.yaml
+`
+}
+
+// generateNSSFConfig generates the NSSF configuration
+func generateNSSFConfig(nfDeployment *nephiov1alpha1.NFDeployment, bindAddress string, identity controllers.NetworkIdentity) string {
+	serviceName := controllers.GetNamespacedName(nfDeployment, nssfServiceName)
+	nrfURI := controllers.GetNRFURI(nfDeployment)
+
+	return fmt.Sprintf(`info:
+  version: 1.0.0
+  description: NSSF initial configuration
+
+configuration:
+  nssfName: NSSF
+  sbi:
+    scheme: http
+    registerIPv4: %s
+    bindingIPv4: "%s"
+    port: %d
+  serviceNameList:
+    - nnssf-nsselection
+    - nnssf-nssaiavailability
+  nrfUri: %s
+  supportedPlmnList:
+%s  supportedNssaiInPlmnList:
+%s  nsiList:
+%s  taList:
+%s`, serviceName, bindAddress, nssfSbiPort, nrfURI, generatePlmnList(identity.PLMNs), generateNssaiInPlmnList(identity),
+		generateNsiList(identity.Slices, nrfURI), generateTaList(identity))
+}
+
+// generatePlmnList renders the PLMNs supported by the NSSF
+func generatePlmnList(plmns []controllers.PLMN) string {
+	var b strings.Builder
+	for _, plmn := range plmns {
+		fmt.Fprintf(&b, "    - mcc: \"%s\"\n      mnc: \"%s\"\n", plmn.MCC, plmn.MNC)
+	}
+	return b.String()
+}
+
+// generateNssaiInPlmnList renders the S-NSSAIs supported in each PLMN
+func generateNssaiInPlmnList(identity controllers.NetworkIdentity) string {
+	var b strings.Builder
+	for _, plmn := range identity.PLMNs {
+		fmt.Fprintf(&b, "    - plmnId:\n        mcc: \"%s\"\n        mnc: \"%s\"\n      supportedSnssaiList:\n", plmn.MCC, plmn.MNC)
+		b.WriteString(generateSnssaiList(identity.Slices, "        "))
+	}
+	return b.String()
+}
+
+// generateTaList renders the S-NSSAIs supported in each tracking area
+func generateTaList(identity controllers.NetworkIdentity) string {
+	var b strings.Builder
+	for _, tai := range identity.TAIs {
+		fmt.Fprintf(&b, "    - tai:\n        plmnId:\n          mcc: \"%s\"\n          mnc: \"%s\"\n        tac: %d\n",
+			tai.PLMN.MCC, tai.PLMN.MNC, tai.TAC)
+		b.WriteString("      accessType: 3GPP_ACCESS\n      supportedSnssaiList:\n")
+		b.WriteString(generateSnssaiList(identity.Slices, "        "))
+	}
+	return b.String()
+}
+
+// generateSnssaiList renders the S-NSSAIs served in the PLMN at the given indentation
+func generateSnssaiList(slices []controllers.Slice, indent string) string {
+	var b strings.Builder
+	for _, slice := range slices {
+		fmt.Fprintf(&b, "%s- sst: %d\n%s  sd: \"%s\"\n", indent, slice.SST, indent, slice.SD)
+	}
+	return b.String()
+}
+
+// generateNsiList renders one network slice instance per S-NSSAI, all served by the same NRF
+func generateNsiList(slices []controllers.Slice, nrfURI string) string {
+	var b strings.Builder
+	for i, slice := range slices {
+		fmt.Fprintf(&b, `    - snssai:
+        sst: %d
+        sd: "%s"
+      nsiInformationList:
+        - nrfId: %s/nnrf-nfm/v1/nf-instances
+          nsiId: %d
+`, slice.SST, slice.SD, nrfURI, i+1)
+	}
+	return b.String()
+}
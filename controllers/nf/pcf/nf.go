@@ -0,0 +1,38 @@
+package pcf
+
+import (
+	"github.com/RohitRathore1/sdcore-operator/controllers"
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func init() {
+	controllers.Register(networkFunction{})
+}
+
+// networkFunction implements controllers.NetworkFunction for the PCF
+type networkFunction struct{}
+
+// Type returns the PCF NF type
+func (networkFunction) Type() controllers.NFType {
+	return controllers.NFTypePCF
+}
+
+// MatchesProvider returns true for the pcf.sdcore.io provider
+func (networkFunction) MatchesProvider(provider string) bool {
+	return controllers.MatchesSDCoreProvider(controllers.NFTypePCF, provider)
+}
+
+// BuildResources returns the ConfigMap, Deployment and Service of the PCF
+func (networkFunction) BuildResources(nfDeployment *nephiov1alpha1.NFDeployment, params *controllers.Parameters) ([]client.Object, error) {
+	configMap, err := buildConfigMap(nfDeployment, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return []client.Object{
+		configMap,
+		buildDeployment(nfDeployment, params),
+		buildService(nfDeployment),
+	}, nil
+}
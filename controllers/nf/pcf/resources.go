@@ -0,0 +1,271 @@
+package pcf
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/RohitRathore1/sdcore-operator/controllers"
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// PCF container names
+	pcfContainerName = "pcf"
+
+	// PCF config and service names
+	pcfConfigName  = "pcf-config"
+	pcfServiceName = "pcf-service"
+
+	// PCF port names
+	pcfSbiPortName  = "sbi"
+	pcfPromPortName = "prometheus"
+
+	// PCF port numbers
+	pcfSbiPort  = 8080
+	pcfPromPort = 9089
+)
+
+// buildConfigMap builds the ConfigMap holding the PCF configuration and run script
+func buildConfigMap(nfDeployment *nephiov1alpha1.NFDeployment, params *controllers.Parameters) (*apiv1.ConfigMap, error) {
+	identity, err := params.NetworkIdentity()
+	if err != nil {
+		return nil, err
+	}
+
+	bindAddress := controllers.SBIBindAddress(controllers.GetIPFamilies(nfDeployment, params))
+	pcfConfig, err := controllers.MergeYAMLConfig(generatePCFConfig(nfDeployment, bindAddress, identity),
+		params.GetOverrides(controllers.NFTypePCF))
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge PCF configuration overrides: %w", err)
+	}
+
+	return &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      controllers.GetNamespacedName(nfDeployment, pcfConfigName),
+			Namespace: nfDeployment.Namespace,
+			Labels: map[string]string{
+				"app": controllers.GetNamespacedName(nfDeployment, "pcf"),
+			},
+		},
+		Data: map[string]string{
+			"pcf-run.sh":  generatePCFRunScript(),
+			"pcfcfg.yaml": pcfConfig,
+		},
+	}, nil
+}
+
+// buildDeployment builds the Deployment for the PCF
+func buildDeployment(nfDeployment *nephiov1alpha1.NFDeployment, params *controllers.Parameters) *appsv1.Deployment {
+	deploymentName := controllers.GetNamespacedName(nfDeployment, "pcf")
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      deploymentName,
+			Namespace: nfDeployment.Namespace,
+			Labels: map[string]string{
+				"app": deploymentName,
+			},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: int32Ptr(1),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": deploymentName,
+				},
+			},
+			Template: apiv1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app": deploymentName,
+					},
+				},
+				Spec: apiv1.PodSpec{
+					Containers: []apiv1.Container{
+						{
+							Name:  pcfContainerName,
+							Image: params.GetImage(controllers.ImagePCF),
+							Ports: []apiv1.ContainerPort{
+								{
+									Name:          pcfSbiPortName,
+									ContainerPort: pcfSbiPort,
+									Protocol:      apiv1.ProtocolTCP,
+								},
+								{
+									Name:          pcfPromPortName,
+									ContainerPort: pcfPromPort,
+									Protocol:      apiv1.ProtocolTCP,
+								},
+							},
+							Command: []string{
+								"/opt/pcf-run.sh",
+							},
+							VolumeMounts: []apiv1.VolumeMount{
+								{
+									Name:      "pcf-config",
+									MountPath: "/opt",
+								},
+							},
+							Env: []apiv1.EnvVar{
+								{
+									Name:  "GRPC_GO_LOG_VERBOSITY_LEVEL",
+									Value: "99",
+								},
+								{
+									Name:  "GRPC_GO_LOG_SEVERITY_LEVEL",
+									Value: "info",
+								},
+								{
+									Name: "POD_IP",
+									ValueFrom: &apiv1.EnvVarSource{
+										FieldRef: &apiv1.ObjectFieldSelector{
+											FieldPath: "status.podIP",
+										},
+									},
+								},
+							},
+							Resources: apiv1.ResourceRequirements{
+								Requests: createResourceList("500m", "512Mi"),
+								Limits:   createResourceList("500m", "512Mi"),
+							},
+							ReadinessProbe: &apiv1.Probe{
+								ProbeHandler: apiv1.ProbeHandler{
+									TCPSocket: &apiv1.TCPSocketAction{
+										Port: intstr.FromInt(pcfSbiPort),
+									},
+								},
+								InitialDelaySeconds: 5,
+								PeriodSeconds:       10,
+							},
+						},
+					},
+					Volumes: []apiv1.Volume{
+						{
+							Name: "pcf-config",
+							VolumeSource: apiv1.VolumeSource{
+								ConfigMap: &apiv1.ConfigMapVolumeSource{
+									LocalObjectReference: apiv1.LocalObjectReference{
+										Name: controllers.GetNamespacedName(nfDeployment, pcfConfigName),
+									},
+									DefaultMode: int32Ptr(0755), // Executable permission for scripts
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// buildService builds the Service exposing the PCF SBI endpoint
+func buildService(nfDeployment *nephiov1alpha1.NFDeployment) *apiv1.Service {
+	selector := map[string]string{
+		"app": controllers.GetNamespacedName(nfDeployment, "pcf"),
+	}
+
+	return &apiv1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      controllers.GetNamespacedName(nfDeployment, pcfServiceName),
+			Namespace: nfDeployment.Namespace,
+			Labels:    selector,
+		},
+		Spec: apiv1.ServiceSpec{
+			Selector: selector,
+			Ports: []apiv1.ServicePort{
+				{
+					Name:       pcfSbiPortName,
+					Port:       pcfSbiPort,
+					TargetPort: intstr.FromInt(pcfSbiPort),
+					Protocol:   apiv1.ProtocolTCP,
+				},
+				{
+					Name:       pcfPromPortName,
+					Port:       pcfPromPort,
+					TargetPort: intstr.FromInt(pcfPromPort),
+					Protocol:   apiv1.ProtocolTCP,
+				},
+			},
+		},
+	}
+}
+
+// Helper functions
+
+// int32Ptr returns a pointer to an int32
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+// createResourceList creates a resource list from CPU and memory values
+func createResourceList(cpu, memory string) apiv1.ResourceList {
+	return apiv1.ResourceList{
+		apiv1.ResourceCPU:    resource.MustParse(cpu),
+		apiv1.ResourceMemory: resource.MustParse(memory),
+	}
+}
+
+// generatePCFRunScript generates the PCF run script
+func generatePCFRunScript() string {
+	return `#!/bin/bash
+cd /free5gc
+./bin/pcf -c /opt/pcfcfg.yaml
+`
+}
+
+// generatePCFConfig generates the PCF configuration
+func generatePCFConfig(nfDeployment *nephiov1alpha1.NFDeployment, bindAddress string, identity controllers.NetworkIdentity) string {
+	serviceName := controllers.GetNamespacedName(nfDeployment, pcfServiceName)
+
+	return fmt.Sprintf(`info:
+  version: 1.0.0
+  description: PCF initial configuration
+
+configuration:
+  pcfName: PCF
+  sbi:
+    scheme: http
+    registerIPv4: %s
+    bindingIPv4: "%s"
+    port: %d
+  timeFormat: 2019-01-02 15:04:05
+  defaultBdtRefId: BdtPolicyId-
+  nrfUri: %s
+  serviceList:
+    - serviceName: npcf-am-policy-control
+    - serviceName: npcf-smpolicycontrol
+      suppFeat: 3fff
+    - serviceName: npcf-bdtpolicycontrol
+    - serviceName: npcf-policyauthorization
+      suppFeat: 3
+    - serviceName: npcf-eventexposure
+    - serviceName: npcf-ue-policy-control
+  mongodb:
+    name: %s
+    url: %s
+  plmnList:
+%s`, serviceName, bindAddress, pcfSbiPort, controllers.GetNRFURI(nfDeployment), controllers.DefaultMongoDBName,
+		controllers.GetMongoDBURL(nfDeployment), generatePlmnList(identity))
+}
+
+// generatePlmnList renders the PLMNs the PCF serves, each with all S-NSSAIs
+func generatePlmnList(identity controllers.NetworkIdentity) string {
+	var b strings.Builder
+	for _, plmn := range identity.PLMNs {
+		fmt.Fprintf(&b, "    - plmnId:\n        mcc: \"%s\"\n        mnc: \"%s\"\n      snssaiList:\n", plmn.MCC, plmn.MNC)
+		b.WriteString(generateSnssaiList(identity.Slices))
+	}
+	return b.String()
+}
+
+// generateSnssaiList renders the S-NSSAIs the PCF provides policies for
+func generateSnssaiList(slices []controllers.Slice) string {
+	var b strings.Builder
+	for _, slice := range slices {
+		fmt.Fprintf(&b, "        - sst: %d\n          sd: \"%s\"\n", slice.SST, slice.SD)
+	}
+	return b.String()
+}
@@ -2,41 +2,143 @@ package nf
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/RohitRathore1/sdcore-operator/controllers"
-	amf "github.com/RohitRathore1/sdcore-operator/controllers/nf/amf"
-	smf "github.com/RohitRathore1/sdcore-operator/controllers/nf/smf"
-	upf "github.com/RohitRathore1/sdcore-operator/controllers/nf/upf"
+	"github.com/RohitRathore1/sdcore-operator/controllers/nfstatus"
 	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+	refv1alpha1 "github.com/nephio-project/api/references/v1alpha1"
 	appsv1 "k8s.io/api/apps/v1"
 	apiv1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
+// networksPollInterval is the interval the NetworkAttachmentDefinition CRD is looked up at while it is missing
+const networksPollInterval = time.Minute
+
 // Reconciles a NFDeployment resource
 type NFDeploymentReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// EnabledNFs restricts the NF types that are reconciled, all registered NF types when empty
+	EnabledNFs []controllers.NFType
+
+	// Images select the images of the network functions, the images parameter of Configs takes precedence
+	Images controllers.ImageSettings
 }
 
 // Sets up the controller with the Manager
 func (r *NFDeploymentReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	// Index NFDeployments by the Configs they reference so Config changes can be mapped back
+	err := mgr.GetFieldIndexer().IndexField(context.Background(), new(nephiov1alpha1.NFDeployment),
+		controllers.ConfigRefIndexKey, func(object client.Object) []string {
+			nfDeployment := object.(*nephiov1alpha1.NFDeployment)
+			var names []string
+			for _, ref := range nfDeployment.Spec.ParametersRefs {
+				if controllers.IsConfigRef(ref) {
+					names = append(names, *ref.Name)
+				}
+			}
+			return names
+		})
+	if err != nil {
+		return err
+	}
+
+	// Owned resources that are changed or deleted are repaired, Configs and peer NFDeployments re-render
+	// the NFDeployments built from them
+	builder := ctrl.NewControllerManagedBy(mgr).
 		For(new(nephiov1alpha1.NFDeployment)).
 		Owns(new(appsv1.Deployment)).
 		Owns(new(apiv1.ConfigMap)).
-		Complete(r)
+		Owns(new(apiv1.Service)).
+		Watches(new(refv1alpha1.Config), handler.EnqueueRequestsFromMapFunc(r.nfDeploymentsForConfig)).
+		Watches(new(nephiov1alpha1.NFDeployment), handler.EnqueueRequestsFromMapFunc(r.nfDeploymentsForPeer))
+
+	// NetworkAttachmentDefinitions can only be watched when Multus is installed
+	gvk := controllers.NetworkAttachmentDefinitionGVK
+	_, err = mgr.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	switch {
+	case err == nil:
+		nad := new(unstructured.Unstructured)
+		nad.SetGroupVersionKind(gvk)
+		builder = builder.Owns(nad)
+	case meta.IsNoMatchError(err):
+		mgr.GetLogger().Info("NetworkAttachmentDefinition CRD not installed, not watching network attachments")
+	default:
+		return err
+	}
+	return builder.Complete(r)
+}
+
+// nfDeploymentsForConfig maps a Config to the NFDeployments referencing it in their parametersRefs
+func (r *NFDeploymentReconciler) nfDeploymentsForConfig(ctx context.Context, config client.Object) []reconcile.Request {
+	nfDeployments := new(nephiov1alpha1.NFDeploymentList)
+	err := r.Client.List(ctx, nfDeployments,
+		client.InNamespace(config.GetNamespace()),
+		client.MatchingFields{controllers.ConfigRefIndexKey: config.GetName()})
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list NFDeployments referencing Config", "Config", config.GetName())
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(nfDeployments.Items))
+	for _, nfDeployment := range nfDeployments.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+			Namespace: nfDeployment.Namespace,
+			Name:      nfDeployment.Name,
+		}})
+	}
+	return requests
+}
+
+// nfDeploymentsForPeer maps an NFDeployment to the NFDeployments built from it, e.g. a UPF to its SMFs
+func (r *NFDeploymentReconciler) nfDeploymentsForPeer(ctx context.Context, object client.Object) []reconcile.Request {
+	peer := object.(*nephiov1alpha1.NFDeployment)
+	if !controllers.IsProviderSDCore(peer.Spec.Provider) {
+		return nil
+	}
+	peerType, err := controllers.ResolveNFType(peer)
+	if err != nil {
+		return nil
+	}
+
+	dependents, err := controllers.ResolveDependents(ctx, r.Client, peer, peerType)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list NFDeployments depending on peer", "NFDeployment", peer.Name)
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(dependents))
+	for _, nfDeployment := range dependents {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+			Namespace: nfDeployment.Namespace,
+			Name:      nfDeployment.Name,
+		}})
+	}
+	return requests
 }
 
 // +kubebuilder:rbac:groups=workload.nephio.org,resources=nfdeployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=workload.nephio.org,resources=nfdeployments/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=workload.nephio.org,resources=nfdeployments/finalizers,verbs=update
 // +kubebuilder:rbac:groups="ref.nephio.org",resources=configs,verbs=get;list;watch
-// +kubebuilder:rbac:groups="k8s.cni.cncf.io",resources=network-attachment-definitions,verbs=get;list;watch
+// +kubebuilder:rbac:groups="k8s.cni.cncf.io",resources=network-attachment-definitions,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps,resources=deployments/status,verbs=get
 // +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
@@ -59,39 +161,302 @@ func (r *NFDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return reconcile.Result{}, err
 	}
 
-	upfReconciler := &upf.UPFDeploymentReconciler{
-		Client: r.Client,
-		Scheme: r.Scheme,
+	if !controllers.IsProviderSDCore(nfDeployment.Spec.Provider) {
+		log.Info("NFDeployment NOT for SDCore", "nfDeployment.Spec.Provider", nfDeployment.Spec.Provider)
+		return reconcile.Result{}, nil
 	}
 
-	smfReconciler := &smf.SMFDeploymentReconciler{
-		Client: r.Client,
-		Scheme: r.Scheme,
+	// Drain UPFs and SMFs in order before they are deleted
+	if nfDeployment.DeletionTimestamp != nil {
+		return r.reconcileDelete(ctrl.LoggerInto(ctx, log), nfDeployment)
 	}
 
-	amfReconciler := &amf.AMFDeploymentReconciler{
-		Client: r.Client,
-		Scheme: r.Scheme,
+	// Resolve the NF type from the label/annotation, provider or interfaces
+	nfType, err := controllers.ResolveNFType(nfDeployment)
+	if err != nil {
+		log.Info("Unable to resolve SDCore NF type", "reason", err.Error())
+		return reconcile.Result{}, r.setCondition(ctx, nfDeployment, controllers.ConditionUnsupported, "UnknownNFType", err)
+	}
+	if err := r.clearCondition(ctx, nfDeployment, controllers.ConditionUnsupported); err != nil {
+		log.Error(err, "Failed to update NFDeployment status")
+		return reconcile.Result{}, err
 	}
 
-	// Route to the appropriate reconciler based on the provider
-	if controllers.IsProviderSDCoreUPF(nfDeployment.Spec.Provider) {
-		log.Info("Routing to UPF reconciler")
-		upfResult, upfErr := upfReconciler.Reconcile(ctx, req)
-		return upfResult, upfErr
-	} else if controllers.IsProviderSDCore(nfDeployment.Spec.Provider) {
-		// For SMF, we'll use the name to determine if it's an SMF deployment
-		if nfDeployment.Name == "test-smf" {
-			log.Info("Routing to SMF reconciler")
-			smfResult, smfErr := smfReconciler.Reconcile(ctx, req)
-			return smfResult, smfErr
-		} else if nfDeployment.Name == "test-amf" {
-			log.Info("Routing to AMF reconciler")
-			amfResult, amfErr := amfReconciler.Reconcile(ctx, req)
-			return amfResult, amfErr
+	nf, ok := controllers.LookupNetworkFunction(nfType)
+	if !ok || !r.isEnabled(nfType) {
+		log.Info("NF type is not enabled, ignoring", "nfType", nfType)
+		return reconcile.Result{}, nil
+	}
+	log = log.WithValues("nfType", nfType)
+	ctx = ctrl.LoggerInto(ctx, log)
+
+	if _, ok := nf.(controllers.Drainable); ok {
+		if err := r.ensureFinalizer(ctx, nfDeployment); err != nil {
+			log.Error(err, "Failed to add finalizer")
+			return reconcile.Result{}, err
 		}
 	}
 
-	log.Info("NFDeployment NOT for SDCore or unsupported type", "nfDeployment.Spec.Provider", nfDeployment.Spec.Provider)
+	// Resolve the parameters from the referenced Configs
+	params, err := controllers.ResolveParameters(ctx, r.Client, nfDeployment)
+	if invalid, ok := controllers.IsInvalidSpec(err); ok {
+		// The NFDeployment is reconciled again once the referenced Configs change
+		log.Info("Invalid parameters", "reason", invalid.Error())
+		return reconcile.Result{}, r.setCondition(ctx, nfDeployment, controllers.ConditionInvalidSpec, invalid.Reason, invalid)
+	}
+	if err != nil {
+		log.Error(err, "Failed to resolve parametersRefs")
+		return reconcile.Result{}, err
+	}
+	params.SetImageDefaults(r.Images)
+	params.Peers, params.DrainingPeers, err = controllers.ResolvePeers(ctx, r.Client, nfDeployment, nf)
+	if err != nil {
+		log.Error(err, "Failed to resolve peer NFDeployments")
+		return reconcile.Result{}, err
+	}
+
+	// Build and apply the resources of the network function
+	objects, err := buildResources(nf, nfDeployment, params)
+	if err == nil {
+		err = r.keepPrimaryIPFamilies(ctx, objects)
+	}
+	if invalid, ok := controllers.IsInvalidSpec(err); ok {
+		// Retrying cannot help, the NFDeployment is reconciled again once its spec or parameters change
+		log.Info("Invalid NFDeployment spec", "reason", invalid.Error())
+		return reconcile.Result{}, r.setCondition(ctx, nfDeployment, controllers.ConditionInvalidSpec, invalid.Reason, invalid)
+	}
+	if err != nil {
+		log.Error(err, "Failed to build resources")
+		return reconcile.Result{}, err
+	}
+	if err := r.clearCondition(ctx, nfDeployment, controllers.ConditionInvalidSpec); err != nil {
+		log.Error(err, "Failed to update NFDeployment status")
+		return reconcile.Result{}, err
+	}
+
+	// Without Multus the NetworkAttachmentDefinitions cannot be applied and the pods would not get their
+	// interfaces, the CRD is looked up again until it is installed
+	attachable, err := r.networkAttachmentsInstalled(objects)
+	if err != nil {
+		log.Error(err, "Failed to look up the NetworkAttachmentDefinition CRD")
+		return reconcile.Result{}, err
+	}
+	if !attachable {
+		log.Info("NetworkAttachmentDefinition CRD not installed, cannot attach the NF interfaces")
+		return reconcile.Result{RequeueAfter: networksPollInterval}, r.setCondition(ctx, nfDeployment,
+			controllers.ConditionNetworksUnavailable, "MultusNotInstalled",
+			fmt.Errorf("the NF interfaces are attached through Multus, but the NetworkAttachmentDefinition CRD is not installed"))
+	}
+	if err := r.clearCondition(ctx, nfDeployment, controllers.ConditionNetworksUnavailable); err != nil {
+		log.Error(err, "Failed to update NFDeployment status")
+		return reconcile.Result{}, err
+	}
+	plan, err := r.planUpgrade(ctx, nfDeployment, nfType, params, objects)
+	if err != nil {
+		log.Error(err, "Failed to plan upgrade")
+		return reconcile.Result{}, err
+	}
+	objects = plan.objects
+
+	changed := false
+	var conflicts []string
+	for _, object := range objects {
+		op, err := r.applyResource(ctx, nfDeployment, object)
+		var controlled *controlledByOtherError
+		if errors.As(err, &controlled) {
+			log.Info("Resource controlled by another owner, skipping", "conflict", controlled.Error())
+			conflicts = append(conflicts, controlled.Error())
+			continue
+		}
+		if err != nil {
+			log.Error(err, "Failed to reconcile resource", "kind", resourceKind(object), "name", object.GetName())
+			return reconcile.Result{}, err
+		}
+		changed = changed || op != controllerutil.OperationResultNone
+	}
+	pruned, err := r.pruneResources(ctx, nfDeployment, objects)
+	if err != nil {
+		log.Error(err, "Failed to prune resources")
+		return reconcile.Result{}, err
+	}
+	changed = changed || pruned
+
+	// Update status
+	deployment := new(appsv1.Deployment)
+	err = r.Client.Get(ctx, types.NamespacedName{
+		Namespace: nfDeployment.Namespace,
+		Name:      controllers.GetNamespacedName(nfDeployment, string(nfType)),
+	}, deployment)
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			log.Error(err, "Failed to get Deployment")
+			return reconcile.Result{}, err
+		}
+		deployment = nil
+	}
+
+	var pods []apiv1.Pod
+	if deployment != nil {
+		pods, err = r.listPods(ctx, deployment)
+		if err != nil {
+			log.Error(err, "Failed to list pods")
+			return reconcile.Result{}, err
+		}
+	}
+	status := nfstatus.Compute(nf, nfDeployment, deployment, pods, params.Peers)
+	if plan.condition != nil {
+		nfstatus.Set(&status, nfDeployment, nfstatus.ConditionUpgraded, plan.condition.Status, plan.condition.Reason,
+			plan.condition.Message)
+	}
+	if len(conflicts) > 0 {
+		nfstatus.Set(&status, nfDeployment, nfstatus.ConditionResourceConflict, metav1.ConditionTrue, "ControlledByOther",
+			strings.Join(conflicts, ", "))
+	} else {
+		meta.RemoveStatusCondition(&status.Conditions, nfstatus.ConditionResourceConflict)
+	}
+	if !nfstatus.Equal(nfDeployment.Status, status) {
+		nfDeployment.Status = status
+		if err := r.Status().Update(ctx, nfDeployment); err != nil {
+			log.Error(err, "Failed to update NFDeployment status")
+			return reconcile.Result{}, err
+		}
+	}
+
+	// Deployment not found yet, requeue
+	if deployment == nil {
+		return reconcile.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+
+	// Watch the readiness of the new version until it rolls out or is rolled back
+	if plan.requeue {
+		return reconcile.Result{RequeueAfter: upgradePollInterval}, nil
+	}
+
+	// Resources controlled by another owner are applied once it releases them
+	if len(conflicts) > 0 {
+		return reconcile.Result{RequeueAfter: conflictPollInterval}, nil
+	}
+
+	// If any resource changed, requeue after a short delay to allow resources to stabilize
+	if changed {
+		log.Info("Resources changed, requeuing")
+		return reconcile.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+
 	return reconcile.Result{}, nil
 }
+
+// isEnabled returns true if the NF type is in the enabled list, an empty list enables every NF type
+func (r *NFDeploymentReconciler) isEnabled(nfType controllers.NFType) bool {
+	if len(r.EnabledNFs) == 0 {
+		return true
+	}
+	for _, enabled := range r.EnabledNFs {
+		if enabled == nfType {
+			return true
+		}
+	}
+	return false
+}
+
+// listPods lists the pods of the Deployment
+func (r *NFDeploymentReconciler) listPods(ctx context.Context, deployment *appsv1.Deployment) ([]apiv1.Pod, error) {
+	if deployment.Spec.Selector == nil {
+		return nil, nil
+	}
+	pods := new(apiv1.PodList)
+	err := r.Client.List(ctx, pods, client.InNamespace(deployment.Namespace),
+		client.MatchingLabels(deployment.Spec.Selector.MatchLabels))
+	if err != nil {
+		return nil, err
+	}
+	return pods.Items, nil
+}
+
+// networkAttachmentsInstalled returns false if NetworkAttachmentDefinitions are among the objects while their
+// CRD is not installed
+func (r *NFDeploymentReconciler) networkAttachmentsInstalled(objects []client.Object) (bool, error) {
+	gvk := controllers.NetworkAttachmentDefinitionGVK
+	for _, object := range objects {
+		if object.GetObjectKind().GroupVersionKind() != gvk {
+			continue
+		}
+		_, err := r.Client.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+		if meta.IsNoMatchError(err) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	return true, nil
+}
+
+// keepPrimaryIPFamilies keeps the primary IP family of the existing Services among the objects, see
+// controllers.KeepPrimaryIPFamily
+func (r *NFDeploymentReconciler) keepPrimaryIPFamilies(ctx context.Context, objects []client.Object) error {
+	for _, object := range objects {
+		service, ok := object.(*apiv1.Service)
+		if !ok {
+			continue
+		}
+		existing := new(apiv1.Service)
+		err := r.Client.Get(ctx, client.ObjectKeyFromObject(service), existing)
+		if k8serrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := controllers.KeepPrimaryIPFamily(service, existing); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildResources builds the resources of the network function for the NFDeployment, including the
+// network attachments of its interfaces, the IP families of its Services, the peers of its Deployment and the
+// configuration hash of its pods
+func buildResources(nf controllers.NetworkFunction, nfDeployment *nephiov1alpha1.NFDeployment,
+	params *controllers.Parameters) ([]client.Object, error) {
+	if err := controllers.ValidateInterfaces(nfDeployment); err != nil {
+		return nil, err
+	}
+	objects, err := nf.BuildResources(nfDeployment, params)
+	if err != nil {
+		return nil, err
+	}
+	// Attach the NF interfaces through Multus
+	objects, err = controllers.WithNetworkAttachments(nfDeployment, params, objects)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build network attachments: %w", err)
+	}
+	objects = controllers.WithIPFamilies(nfDeployment, params, objects)
+	// Record the peers the configuration is built from, the teardown of a peer waits for them to drop it
+	objects = controllers.WithPeers(objects, params.Peers)
+	// Roll the pods when the configuration they read at startup changes
+	return controllers.WithConfigHash(objects), nil
+}
+
+// setCondition records on the NFDeployment status why it cannot be reconciled, e.g. that its NF type
+// could not be resolved or its spec is invalid. Reconciliation is stalled until the NFDeployment changes.
+func (r *NFDeploymentReconciler) setCondition(ctx context.Context, nfDeployment *nephiov1alpha1.NFDeployment,
+	conditionType, reason string, cause error) error {
+	status := *nfDeployment.Status.DeepCopy()
+	nfstatus.Set(&status, nfDeployment, conditionType, metav1.ConditionTrue, reason, cause.Error())
+	nfstatus.SetStalled(&status, nfDeployment, reason, cause.Error())
+	if nfstatus.Equal(nfDeployment.Status, status) {
+		return nil
+	}
+	nfDeployment.Status = status
+	return r.Status().Update(ctx, nfDeployment)
+}
+
+// clearCondition removes a previously set condition once the NFDeployment can be reconciled again
+func (r *NFDeploymentReconciler) clearCondition(ctx context.Context, nfDeployment *nephiov1alpha1.NFDeployment,
+	conditionType string) error {
+	if meta.FindStatusCondition(nfDeployment.Status.Conditions, conditionType) == nil {
+		return nil
+	}
+	meta.RemoveStatusCondition(&nfDeployment.Status.Conditions, conditionType)
+	return r.Status().Update(ctx, nfDeployment)
+}
@@ -0,0 +1,71 @@
+package nf
+
+import (
+	"fmt"
+
+	"github.com/RohitRathore1/sdcore-operator/controllers"
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+	refv1alpha1 "github.com/nephio-project/api/references/v1alpha1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Render builds the resources the reconciler applies for an SD-Core NFDeployment without a cluster. The
+// parameters are merged from the referenced Configs among configs and the peers are selected among
+// nfDeployments. Only the upgrade record of the first rollout and the owner references, which depend on
+// the state of the cluster, are left out.
+func Render(nfDeployment *nephiov1alpha1.NFDeployment, configs []refv1alpha1.Config,
+	nfDeployments []nephiov1alpha1.NFDeployment, images controllers.ImageSettings) ([]client.Object, error) {
+	nfType, err := controllers.ResolveNFType(nfDeployment)
+	if err != nil {
+		return nil, err
+	}
+	nf, ok := controllers.LookupNetworkFunction(nfType)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", controllers.ErrUnsupportedNFType, nfType)
+	}
+
+	params, err := RenderParameters(nfDeployment, configs)
+	if err != nil {
+		return nil, err
+	}
+	params.SetImageDefaults(images)
+	params.Peers = controllers.SelectPeers(nfDeployment, nf, nfDeployments)
+	params.DrainingPeers = controllers.SelectDrainingPeers(nfDeployment, nf, nfDeployments)
+
+	objects, err := buildResources(nf, nfDeployment, params)
+	if err != nil {
+		return nil, err
+	}
+	if deployment := findDeployment(objects); deployment != nil {
+		setRollout(deployment, params.GetUpgrade())
+	}
+	return objects, nil
+}
+
+// RenderParameters merges the parameters of the Configs referenced by the NFDeployment, found among configs
+func RenderParameters(nfDeployment *nephiov1alpha1.NFDeployment, configs []refv1alpha1.Config) (*controllers.Parameters, error) {
+	var referenced []refv1alpha1.Config
+	for _, ref := range nfDeployment.Spec.ParametersRefs {
+		if !controllers.IsConfigRef(ref) {
+			continue
+		}
+		key := types.NamespacedName{Namespace: nfDeployment.Namespace, Name: *ref.Name}
+		config := findConfig(configs, key)
+		if config == nil {
+			return nil, fmt.Errorf("referenced Config %s not found", key)
+		}
+		referenced = append(referenced, *config)
+	}
+	return controllers.MergeConfigs(referenced)
+}
+
+// findConfig returns the Config with the given key
+func findConfig(configs []refv1alpha1.Config, key types.NamespacedName) *refv1alpha1.Config {
+	for i := range configs {
+		if configs[i].Namespace == key.Namespace && configs[i].Name == key.Name {
+			return &configs[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package smf
+
+import (
+	"time"
+
+	"github.com/RohitRathore1/sdcore-operator/controllers"
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func init() {
+	controllers.Register(networkFunction{})
+}
+
+// networkFunction implements controllers.NetworkFunction for the SMF
+type networkFunction struct{}
+
+// Type returns the SMF NF type
+func (networkFunction) Type() controllers.NFType {
+	return controllers.NFTypeSMF
+}
+
+// MatchesProvider returns true for the smf.sdcore.io provider
+func (networkFunction) MatchesProvider(provider string) bool {
+	return controllers.MatchesSDCoreProvider(controllers.NFTypeSMF, provider)
+}
+
+// PeerTypes returns the UPF NF type, the SMF userplane is built from the UPF NFDeployments
+func (networkFunction) PeerTypes() []controllers.NFType {
+	return []controllers.NFType{controllers.NFTypeUPF}
+}
+
+// DrainTimeout returns the time the SMF teardown waits for its dependents, it has none and stops serving
+// by scaling down, which releases its PFCP associations
+func (networkFunction) DrainTimeout() time.Duration {
+	return 0
+}
+
+// ValidateSpec requires the N4 interface the SMF associates with the UPFs from
+func (networkFunction) ValidateSpec(nfDeployment *nephiov1alpha1.NFDeployment) field.ErrorList {
+	return controllers.RequireInterfaces(nfDeployment, "n4")
+}
+
+// BuildResources returns the ConfigMap, Deployment and Service of the SMF
+func (networkFunction) BuildResources(nfDeployment *nephiov1alpha1.NFDeployment, params *controllers.Parameters) ([]client.Object, error) {
+	configMap, err := buildConfigMap(nfDeployment, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return []client.Object{
+		configMap,
+		buildDeployment(nfDeployment, params),
+		buildService(nfDeployment),
+	}, nil
+}
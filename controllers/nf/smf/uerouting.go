@@ -0,0 +1,145 @@
+package smf
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/RohitRathore1/sdcore-operator/controllers"
+	"sigs.k8s.io/yaml"
+)
+
+// supiPattern matches an IMSI based SUPI
+var supiPattern = regexp.MustCompile(`^imsi-[0-9]{5,15}$`)
+
+// ueRoutingDocument is the uerouting.yaml read by the SMF
+type ueRoutingDocument struct {
+	Info struct {
+		Version     string `json:"version"`
+		Description string `json:"description"`
+	} `json:"info"`
+	controllers.UERouting `json:",inline"`
+}
+
+// generateUERoutingConfig renders the UE routing policy as uerouting.yaml
+func generateUERoutingConfig(routing controllers.UERouting) (string, error) {
+	document := ueRoutingDocument{UERouting: routing}
+	document.Info.Version = "1.0.0"
+	document.Info.Description = "Routing information for UE"
+
+	rendered, err := yaml.Marshal(document)
+	if err != nil {
+		return "", err
+	}
+	return string(rendered), nil
+}
+
+// reasonInvalidUERouting is the InvalidSpec reason for a UE routing policy the SMF cannot be configured with
+const reasonInvalidUERouting = "InvalidUERouting"
+
+// pruneUERouting returns the UE routing policy without the paths through the draining UPFs, those being
+// deleted, so that the SMF stops routing through them
+func pruneUERouting(routing controllers.UERouting, draining map[string]bool) controllers.UERouting {
+	pruned := routing
+	pruned.UERoutingInfo = make([]controllers.UERoutingInfo, 0, len(routing.UERoutingInfo))
+	for _, info := range routing.UERoutingInfo {
+		pathList := make([]controllers.RoutingPath, 0, len(info.PathList))
+		for _, path := range info.PathList {
+			through := false
+			for _, upf := range path.UPF {
+				through = through || draining[upf]
+			}
+			if !through {
+				pathList = append(pathList, path)
+			}
+		}
+		info.PathList = pathList
+		pruned.UERoutingInfo = append(pruned.UERoutingInfo, info)
+	}
+	return pruned
+}
+
+// validateUERouting checks the UE routing policy is consistent and only routes through known UPFs, the
+// userplane nodes and the draining UPFs
+func validateUERouting(routing controllers.UERouting, upNodes []upNode, draining map[string]bool) error {
+	known := map[string]bool{}
+	for _, node := range upNodes {
+		known[node.Name] = true
+	}
+
+	supis := map[string]bool{}
+	for i, info := range routing.UERoutingInfo {
+		if !supiPattern.MatchString(info.SUPI) {
+			return fmt.Errorf("ueRoutingInfo[%d]: invalid SUPI %q, expected imsi-<digits>", i, info.SUPI)
+		}
+		if supis[info.SUPI] {
+			return fmt.Errorf("ueRoutingInfo[%d]: duplicate SUPI %s", i, info.SUPI)
+		}
+		supis[info.SUPI] = true
+		if info.AN != "" && net.ParseIP(info.AN) == nil {
+			return fmt.Errorf("ueRoutingInfo[%d]: invalid AN address %q", i, info.AN)
+		}
+		for j, path := range info.PathList {
+			if _, _, err := net.ParseCIDR(path.DestinationIP); err != nil {
+				return fmt.Errorf("ueRoutingInfo[%d].PathList[%d]: invalid DestinationIP %q", i, j, path.DestinationIP)
+			}
+			if len(path.UPF) == 0 {
+				return fmt.Errorf("ueRoutingInfo[%d].PathList[%d]: no UPF in path", i, j)
+			}
+			for _, upf := range path.UPF {
+				if !known[upf] && !draining[upf] {
+					return fmt.Errorf("ueRoutingInfo[%d].PathList[%d]: unknown UPF %q", i, j, upf)
+				}
+			}
+		}
+	}
+
+	profiles := map[string]bool{}
+	for i, profile := range routing.RouteProfile {
+		if profile.RouteProfileID == "" {
+			return fmt.Errorf("routeProfile[%d]: missing RouteProfileID", i)
+		}
+		if profiles[profile.RouteProfileID] {
+			return fmt.Errorf("routeProfile[%d]: duplicate RouteProfileID %s", i, profile.RouteProfileID)
+		}
+		profiles[profile.RouteProfileID] = true
+	}
+
+	applications := map[string]bool{}
+	for i, app := range routing.PFDDataForApp {
+		if app.ApplicationID == "" {
+			return fmt.Errorf("pfdDataForApp[%d]: missing applicationId", i)
+		}
+		if applications[app.ApplicationID] {
+			return fmt.Errorf("pfdDataForApp[%d]: duplicate applicationId %s", i, app.ApplicationID)
+		}
+		applications[app.ApplicationID] = true
+		for j, pfd := range app.PFDs {
+			if pfd.PFDID == "" {
+				return fmt.Errorf("pfdDataForApp[%d].pfds[%d]: missing pfdID", i, j)
+			}
+			if len(pfd.FlowDescriptions)+len(pfd.URLs)+len(pfd.DomainNames) == 0 {
+				return fmt.Errorf("pfdDataForApp[%d].pfds[%d]: no flowDescriptions, urls or domainNames", i, j)
+			}
+			for _, flow := range pfd.FlowDescriptions {
+				if !strings.HasPrefix(flow, "permit ") {
+					return fmt.Errorf("pfdDataForApp[%d].pfds[%d]: flow description %q must start with permit", i, j, flow)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// usesULCL returns true if a UE is routed through an uplink classifier, i.e. a path with a branching UPF
+func usesULCL(routing controllers.UERouting) bool {
+	for _, info := range routing.UERoutingInfo {
+		for _, path := range info.PathList {
+			if len(path.UPF) > 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
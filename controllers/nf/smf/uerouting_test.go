@@ -0,0 +1,38 @@
+package smf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/RohitRathore1/sdcore-operator/controllers"
+)
+
+// TestUERoutingUnknownUPFs checks paths through a UPF being deleted are dropped while a path naming a UPF
+// that does not exist is reported
+func TestUERoutingUnknownUPFs(t *testing.T) {
+	upNodes := []upNode{{Name: "branching-upf"}, {Name: "anchor-upf"}}
+	draining := map[string]bool{"edge-upf": true}
+	routing := func(upfs ...string) controllers.UERouting {
+		return controllers.UERouting{UERoutingInfo: []controllers.UERoutingInfo{{
+			SUPI: "imsi-208930000000003",
+			PathList: []controllers.RoutingPath{
+				{DestinationIP: "172.250.0.0/16", UPF: []string{"branching-upf", "anchor-upf"}},
+				{DestinationIP: "10.100.0.0/16", UPF: upfs},
+			},
+		}}}
+	}
+
+	drained := routing("branching-upf", "edge-upf")
+	if err := validateUERouting(drained, upNodes, draining); err != nil {
+		t.Fatalf("path through a draining UPF rejected: %v", err)
+	}
+	pruned := pruneUERouting(drained, draining)
+	if paths := pruned.UERoutingInfo[0].PathList; len(paths) != 1 || paths[0].DestinationIP != "172.250.0.0/16" {
+		t.Errorf("path through the draining UPF not dropped: %v", paths)
+	}
+
+	err := validateUERouting(routing("branching-upf", "egde-upf"), upNodes, draining)
+	if err == nil || !strings.Contains(err.Error(), `unknown UPF "egde-upf"`) {
+		t.Errorf("path through an unknown UPF not reported: %v", err)
+	}
+}
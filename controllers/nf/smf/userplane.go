@@ -0,0 +1,156 @@
+package smf
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/RohitRathore1/sdcore-operator/controllers"
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+)
+
+// anNodeName is the access network node every UPF is linked to
+const anNodeName = "gNB1"
+
+// upNode is a UPF of the SMF userplane_information
+type upNode struct {
+	// Name is the name of the UPF NFDeployment
+	Name string
+	// NodeID is the N4 address the SMF associates with over PFCP
+	NodeID string
+	// N3Address is the GTP-U endpoint of the UPF towards the RAN
+	N3Address string
+	// DataNetworks are the DNNs served by the UPF with their UE pools
+	DataNetworks []upDataNetwork
+}
+
+// upDataNetwork is a DNN served by a UPF
+type upDataNetwork struct {
+	Name  string
+	Pools []string
+}
+
+// serves returns the data network of the UPF with the given DNN
+func (n upNode) serves(dnn string) (upDataNetwork, bool) {
+	for _, dataNetwork := range n.DataNetworks {
+		if dataNetwork.Name == dnn {
+			return dataNetwork, true
+		}
+	}
+	return upDataNetwork{}, false
+}
+
+// buildUPNodes builds the userplane nodes from the UPF NFDeployments, UPFs without an N4 address are skipped
+func buildUPNodes(upfs []nephiov1alpha1.NFDeployment) ([]upNode, error) {
+	nodes := make([]upNode, 0, len(upfs))
+	for i := range upfs {
+		upf := &upfs[i]
+		n4Address, ok, err := controllers.InterfaceIP(upf, "n4")
+		if err != nil {
+			return nil, fmt.Errorf("UPF %s: %w", upf.Name, err)
+		}
+		if !ok {
+			continue
+		}
+		n3Address, ok, err := controllers.InterfaceIP(upf, "n3")
+		if err != nil {
+			return nil, fmt.Errorf("UPF %s: %w", upf.Name, err)
+		}
+		node := upNode{
+			Name:   upf.Name,
+			NodeID: n4Address.String(),
+		}
+		if ok {
+			node.N3Address = n3Address.String()
+		}
+		for _, networkInstance := range upf.Spec.NetworkInstances {
+			for _, dataNetwork := range networkInstance.DataNetworks {
+				if dataNetwork.Name == nil {
+					continue
+				}
+				dn := upDataNetwork{Name: *dataNetwork.Name}
+				for _, pool := range dataNetwork.Pool {
+					dn.Pools = append(dn.Pools, pool.Prefix)
+				}
+				node.DataNetworks = append(node.DataNetworks, dn)
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// buildLinks returns the links of the userplane: the access network node is linked to every UPF, and the
+// consecutive UPFs of every UE routing path, from the branching UPF to the anchor UPF, are linked to each other
+func buildLinks(nodes []upNode, routing controllers.UERouting) [][2]string {
+	links := make([][2]string, 0, len(nodes))
+	for _, node := range nodes {
+		links = append(links, [2]string{anNodeName, node.Name})
+	}
+	linked := map[[2]string]bool{}
+	for _, info := range routing.UERoutingInfo {
+		for _, path := range info.PathList {
+			for i := 1; i < len(path.UPF); i++ {
+				link := [2]string{path.UPF[i-1], path.UPF[i]}
+				if link[0] == link[1] || linked[link] || linked[[2]string{link[1], link[0]}] {
+					continue
+				}
+				linked[link] = true
+				links = append(links, link)
+			}
+		}
+	}
+	return links
+}
+
+// generateUserplaneInformation renders the SMF userplane_information with the links of buildLinks. Each UPF
+// serves the slices whose DNNs it declares in its network instances.
+func generateUserplaneInformation(nodes []upNode, slices []controllers.Slice, routing controllers.UERouting) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "  userplane_information:\n    up_nodes:\n      %s:\n        type: AN\n", anNodeName)
+	for _, node := range nodes {
+		fmt.Fprintf(&b, "      %s:\n        type: UPF\n        node_id: %s\n", node.Name, node.NodeID)
+		var infos strings.Builder
+		for _, slice := range slices {
+			var served []upDataNetwork
+			for _, dnn := range slice.DNNs {
+				if dataNetwork, ok := node.serves(dnn); ok {
+					served = append(served, dataNetwork)
+				}
+			}
+			if len(served) == 0 {
+				continue
+			}
+			fmt.Fprintf(&infos, "          - sNssai:\n              sst: %d\n              sd: \"%s\"\n            dnnUpfInfoList:\n",
+				slice.SST, slice.SD)
+			for _, dataNetwork := range served {
+				fmt.Fprintf(&infos, "              - dnn: %s\n", dataNetwork.Name)
+				if len(dataNetwork.Pools) > 0 {
+					infos.WriteString("                pools:\n")
+					for _, pool := range dataNetwork.Pools {
+						fmt.Fprintf(&infos, "                  - cidr: %s\n", pool)
+					}
+				}
+			}
+		}
+		if infos.Len() > 0 {
+			fmt.Fprintf(&b, "        sNssaiUpfInfos:\n%s", infos.String())
+		}
+		if node.N3Address != "" {
+			fmt.Fprintf(&b, "        interfaces:\n          - interfaceType: N3\n            endpoints:\n              - %s\n",
+				node.N3Address)
+			if len(node.DataNetworks) > 0 {
+				fmt.Fprintf(&b, "            networkInstance: %s\n", node.DataNetworks[0].Name)
+			}
+		}
+	}
+
+	if len(nodes) == 0 {
+		b.WriteString("    links: []\n")
+		return b.String()
+	}
+	b.WriteString("    links:\n")
+	for _, link := range buildLinks(nodes, routing) {
+		fmt.Fprintf(&b, "      - A: %s\n        B: %s\n", link[0], link[1])
+	}
+	return b.String()
+}
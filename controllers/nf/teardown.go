@@ -0,0 +1,173 @@
+package nf
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/RohitRathore1/sdcore-operator/controllers"
+	"github.com/RohitRathore1/sdcore-operator/controllers/nfstatus"
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// TeardownFinalizer holds the deleted NFDeployments of drainable network functions until they are drained
+	TeardownFinalizer = "nf.sdcore.io/teardown"
+
+	// teardownPollInterval is the interval the progress of a teardown is checked at
+	teardownPollInterval = 5 * time.Second
+)
+
+// ensureFinalizer adds the teardown finalizer to the NFDeployment
+func (r *NFDeploymentReconciler) ensureFinalizer(ctx context.Context, nfDeployment *nephiov1alpha1.NFDeployment) error {
+	if !controllerutil.AddFinalizer(nfDeployment, TeardownFinalizer) {
+		return nil
+	}
+	return r.Client.Update(ctx, nfDeployment)
+}
+
+// removeFinalizer removes the teardown finalizer, the owned resources are then garbage collected
+// with the NFDeployment
+func (r *NFDeploymentReconciler) removeFinalizer(ctx context.Context, nfDeployment *nephiov1alpha1.NFDeployment) error {
+	if !controllerutil.RemoveFinalizer(nfDeployment, TeardownFinalizer) {
+		return nil
+	}
+	return r.Client.Update(ctx, nfDeployment)
+}
+
+// reconcileDelete tears down a deleted NFDeployment holding the teardown finalizer. The finalizer is
+// dropped right away when the NFDeployment no longer resolves to a drainable network function.
+func (r *NFDeploymentReconciler) reconcileDelete(ctx context.Context, nfDeployment *nephiov1alpha1.NFDeployment) (ctrl.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+	if !controllerutil.ContainsFinalizer(nfDeployment, TeardownFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	nfType, err := controllers.ResolveNFType(nfDeployment)
+	if err != nil {
+		return ctrl.Result{}, r.removeFinalizer(ctx, nfDeployment)
+	}
+	if !r.isEnabled(nfType) {
+		log.Info("NF type is not enabled, leaving teardown to the operator reconciling it", "nfType", nfType)
+		return ctrl.Result{}, nil
+	}
+	nf, ok := controllers.LookupNetworkFunction(nfType)
+	if !ok {
+		return ctrl.Result{}, r.removeFinalizer(ctx, nfDeployment)
+	}
+	drainable, ok := nf.(controllers.Drainable)
+	if !ok {
+		return ctrl.Result{}, r.removeFinalizer(ctx, nfDeployment)
+	}
+	log = log.WithValues("nfType", nfType)
+
+	// Wait for the NFDeployments built from the network function to stop using it
+	pending, err := r.pendingDependents(ctx, nfDeployment, nfType)
+	if err != nil {
+		log.Error(err, "Failed to resolve dependent NFDeployments")
+		return ctrl.Result{}, err
+	}
+	if len(pending) > 0 {
+		if time.Since(nfDeployment.DeletionTimestamp.Time) < drainable.DrainTimeout() {
+			return r.setTerminating(ctx, nfDeployment, "Draining",
+				fmt.Sprintf("Waiting for %s to stop using the %s", strings.Join(pending, ", "), strings.ToUpper(string(nfType))))
+		}
+		log.Info("Drain timeout exceeded, scaling down", "pending", pending)
+	}
+
+	// Scale down and wait for the pods to shut down gracefully
+	deployment := new(appsv1.Deployment)
+	err = r.Client.Get(ctx, types.NamespacedName{
+		Namespace: nfDeployment.Namespace,
+		Name:      controllers.GetNamespacedName(nfDeployment, string(nfType)),
+	}, deployment)
+	switch {
+	case k8serrors.IsNotFound(err):
+	case err != nil:
+		log.Error(err, "Failed to get Deployment")
+		return ctrl.Result{}, err
+	default:
+		if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas != 0 {
+			patch := client.MergeFrom(deployment.DeepCopy())
+			replicas := int32(0)
+			deployment.Spec.Replicas = &replicas
+			if err := r.Client.Patch(ctx, deployment, patch, client.FieldOwner(FieldManager)); err != nil {
+				log.Error(err, "Failed to scale down Deployment")
+				return ctrl.Result{}, err
+			}
+			log.Info("Deployment scaled down")
+		}
+		pods, err := r.listPods(ctx, deployment)
+		if err != nil {
+			log.Error(err, "Failed to list pods")
+			return ctrl.Result{}, err
+		}
+		if len(pods) > 0 {
+			return r.setTerminating(ctx, nfDeployment, "ScalingDown",
+				fmt.Sprintf("Waiting for %d pods to terminate", len(pods)))
+		}
+	}
+
+	log.Info("NFDeployment drained, removing finalizer")
+	return ctrl.Result{}, r.removeFinalizer(ctx, nfDeployment)
+}
+
+// pendingDependents returns the NFDeployments built from the NFDeployment that still use it: those being
+// deleted themselves, and those whose Deployment has not rolled out a configuration built without it
+func (r *NFDeploymentReconciler) pendingDependents(ctx context.Context, nfDeployment *nephiov1alpha1.NFDeployment,
+	nfType controllers.NFType) ([]string, error) {
+	dependents, err := controllers.ResolveDependents(ctx, r.Client, nfDeployment, nfType)
+	if err != nil {
+		return nil, err
+	}
+	var pending []string
+	for _, dependent := range dependents {
+		name := fmt.Sprintf("%s/%s", dependent.Namespace, dependent.Name)
+		if dependent.DeletionTimestamp != nil {
+			pending = append(pending, name)
+			continue
+		}
+		dependentType, err := controllers.ResolveNFType(&dependent)
+		if err != nil {
+			continue
+		}
+		deployment := new(appsv1.Deployment)
+		err = r.Client.Get(ctx, types.NamespacedName{
+			Namespace: dependent.Namespace,
+			Name:      controllers.GetNamespacedName(&dependent, string(dependentType)),
+		}, deployment)
+		if k8serrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !controllers.RolledOut(deployment) || controllers.BuiltFrom(deployment, nfDeployment) {
+			pending = append(pending, name)
+		}
+	}
+	return pending, nil
+}
+
+// setTerminating reports the teardown progress on the NFDeployment status and requeues it
+func (r *NFDeploymentReconciler) setTerminating(ctx context.Context, nfDeployment *nephiov1alpha1.NFDeployment,
+	reason, message string) (ctrl.Result, error) {
+	status := *nfDeployment.Status.DeepCopy()
+	nfstatus.Set(&status, nfDeployment, nfstatus.ConditionTerminating, metav1.ConditionTrue, reason, message)
+	nfstatus.Set(&status, nfDeployment, nfstatus.ConditionReady, metav1.ConditionFalse, "Terminating", "The NF is being deleted")
+	if !nfstatus.Equal(nfDeployment.Status, status) {
+		nfDeployment.Status = status
+		if err := r.Status().Update(ctx, nfDeployment); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{RequeueAfter: teardownPollInterval}, nil
+}
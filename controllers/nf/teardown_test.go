@@ -0,0 +1,151 @@
+package nf
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/RohitRathore1/sdcore-operator/controllers"
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+	refv1alpha1 "github.com/nephio-project/api/references/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	runscheme "sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+// newTestReconciler returns a reconciler reading the objects from a fake client
+func newTestReconciler(t *testing.T, objects ...client.Object) *NFDeploymentReconciler {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	nfDeployments := &runscheme.Builder{GroupVersion: nephiov1alpha1.GroupVersion}
+	nfDeployments.Register(&nephiov1alpha1.NFDeployment{}, &nephiov1alpha1.NFDeploymentList{})
+	configs := &runscheme.Builder{GroupVersion: refv1alpha1.GroupVersion}
+	configs.Register(&refv1alpha1.Config{}, &refv1alpha1.ConfigList{})
+	for _, builder := range []*runscheme.Builder{nfDeployments, configs} {
+		if err := builder.AddToScheme(scheme); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	// The NetworkAttachmentDefinition CRD is installed
+	mapper := meta.NewDefaultRESTMapper(nil)
+	for gvk := range scheme.AllKnownTypes() {
+		mapper.Add(gvk, meta.RESTScopeNamespace)
+	}
+	mapper.Add(controllers.NetworkAttachmentDefinitionGVK, meta.RESTScopeNamespace)
+	return &NFDeploymentReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithRESTMapper(mapper).WithObjects(objects...).Build(),
+		Scheme: scheme,
+	}
+}
+
+// testNFDeployment returns an SD-Core NFDeployment of the provider with IPv4 interfaces, as name=address/prefix
+func testNFDeployment(name, provider string, interfaces ...string) *nephiov1alpha1.NFDeployment {
+	nfDeployment := &nephiov1alpha1.NFDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID("uid-" + name)},
+		Spec:       nephiov1alpha1.NFDeploymentSpec{Provider: provider},
+	}
+	for _, value := range interfaces {
+		interfaceName, address, _ := strings.Cut(value, "=")
+		nfDeployment.Spec.Interfaces = append(nfDeployment.Spec.Interfaces, nephiov1alpha1.InterfaceConfig{
+			Name: interfaceName,
+			IPv4: &nephiov1alpha1.IPv4{Address: address},
+		})
+	}
+	return nfDeployment
+}
+
+// TestTeardownWhileImagePinned checks a UPF being deleted is held until its SMF rolls out a configuration
+// without it, while the SMF images are pinned after a rolled back upgrade
+func TestTeardownWhileImagePinned(t *testing.T) {
+	ctx := context.Background()
+	deleted := testNFDeployment("upf-a", "upf.sdcore.io", "n3=192.168.252.3/24", "n4=192.168.250.3/24")
+	deleted.DeletionTimestamp = &metav1.Time{Time: metav1.Now().Time}
+	deleted.Finalizers = []string{TeardownFinalizer}
+	remaining := testNFDeployment("upf-b", "upf.sdcore.io", "n3=192.168.252.5/24", "n4=192.168.250.5/24")
+	smf := testNFDeployment("smf", "smf.sdcore.io", "n4=192.168.250.4/24")
+
+	nf, ok := controllers.LookupNetworkFunction(controllers.NFTypeSMF)
+	if !ok {
+		t.Fatal("SMF not registered")
+	}
+	params := &controllers.Parameters{}
+	params.SetImageDefaults(controllers.ImageSettings{})
+	params.Peers = controllers.SelectPeers(smf, nf, []nephiov1alpha1.NFDeployment{*deleted, *remaining})
+
+	// The last upgrade of the SMF to the selected images was rolled back, the record of its running
+	// revision is the one before the UPF was deleted
+	pinned := "omecproject/5gc-smf:rel-1.5.1"
+	upgrade := upgradeRecord{
+		Current:      &revision{Release: "1.5", Images: map[string]string{"smf": pinned}, ConfigHash: "0123456789abcdef"},
+		FailedImages: map[string]string{"smf": params.GetImage(controllers.ImageSMF)},
+	}
+	data, err := json.Marshal(upgrade)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "smf-smf-upgrade", Namespace: "default"},
+		Data:       map[string]string{upgradeRecordKey: string(data)},
+	}
+
+	objects, err := buildResources(nf, smf, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newTestReconciler(t, deleted, remaining, smf, record)
+	plan, err := r.planUpgrade(ctx, smf, controllers.NFTypeSMF, params, objects)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deployment := findDeployment(plan.objects)
+	if image := deploymentImages(deployment)["smf"]; image != pinned {
+		t.Errorf("SMF image is %s, want the pinned %s", image, pinned)
+	}
+	configured := false
+	for _, object := range plan.objects {
+		configMap, ok := object.(*apiv1.ConfigMap)
+		if !ok || configMap.Name != "smf-smf-config" {
+			continue
+		}
+		configured = true
+		if config := configMap.Data["smfcfg.yaml"]; strings.Contains(config, "upf-a") || !strings.Contains(config, "upf-b") {
+			t.Errorf("SMF configuration is not built from the remaining UPF:\n%s", config)
+		}
+	}
+	if !configured {
+		t.Error("SMF configuration not rendered")
+	}
+	if controllers.BuiltFrom(deployment, deleted) {
+		t.Errorf("SMF Deployment records the deleted UPF among its peers: %s", deployment.Annotations[controllers.PeersAnnotation])
+	}
+
+	// The UPF is drained once the SMF pods run the configuration without it
+	for _, rolledOut := range []bool{false, true} {
+		applied := deployment.DeepCopy()
+		applied.Generation = 2
+		applied.Status = appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 1, UpdatedReplicas: 1}
+		if rolledOut {
+			applied.Status.ObservedGeneration = 2
+		}
+		r := newTestReconciler(t, deleted, remaining, smf, applied)
+		pending, err := r.pendingDependents(ctx, deleted, controllers.NFTypeUPF)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rolledOut != (len(pending) == 0) {
+			t.Errorf("rolled out %t: pending dependents %v", rolledOut, pending)
+		}
+	}
+}
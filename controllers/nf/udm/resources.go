@@ -0,0 +1,257 @@
+package udm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/RohitRathore1/sdcore-operator/controllers"
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// UDM container names
+	udmContainerName = "udm"
+
+	// UDM config and service names
+	udmConfigName  = "udm-config"
+	udmServiceName = "udm-service"
+
+	// UDM port names
+	udmSbiPortName  = "sbi"
+	udmPromPortName = "prometheus"
+
+	// UDM port numbers
+	udmSbiPort  = 8080
+	udmPromPort = 9089
+)
+
+// buildConfigMap builds the ConfigMap holding the UDM configuration and run script
+func buildConfigMap(nfDeployment *nephiov1alpha1.NFDeployment, params *controllers.Parameters) (*apiv1.ConfigMap, error) {
+	identity, err := params.NetworkIdentity()
+	if err != nil {
+		return nil, err
+	}
+
+	bindAddress := controllers.SBIBindAddress(controllers.GetIPFamilies(nfDeployment, params))
+	udmConfig, err := controllers.MergeYAMLConfig(generateUDMConfig(nfDeployment, bindAddress, identity),
+		params.GetOverrides(controllers.NFTypeUDM))
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge UDM configuration overrides: %w", err)
+	}
+
+	return &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      controllers.GetNamespacedName(nfDeployment, udmConfigName),
+			Namespace: nfDeployment.Namespace,
+			Labels: map[string]string{
+				"app": controllers.GetNamespacedName(nfDeployment, "udm"),
+			},
+		},
+		Data: map[string]string{
+			"udm-run.sh":  generateUDMRunScript(),
+			"udmcfg.yaml": udmConfig,
+		},
+	}, nil
+}
+
+// buildDeployment builds the Deployment for the UDM
+func buildDeployment(nfDeployment *nephiov1alpha1.NFDeployment, params *controllers.Parameters) *appsv1.Deployment {
+	deploymentName := controllers.GetNamespacedName(nfDeployment, "udm")
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      deploymentName,
+			Namespace: nfDeployment.Namespace,
+			Labels: map[string]string{
+				"app": deploymentName,
+			},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: int32Ptr(1),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": deploymentName,
+				},
+			},
+			Template: apiv1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app": deploymentName,
+					},
+				},
+				Spec: apiv1.PodSpec{
+					Containers: []apiv1.Container{
+						{
+							Name:  udmContainerName,
+							Image: params.GetImage(controllers.ImageUDM),
+							Ports: []apiv1.ContainerPort{
+								{
+									Name:          udmSbiPortName,
+									ContainerPort: udmSbiPort,
+									Protocol:      apiv1.ProtocolTCP,
+								},
+								{
+									Name:          udmPromPortName,
+									ContainerPort: udmPromPort,
+									Protocol:      apiv1.ProtocolTCP,
+								},
+							},
+							Command: []string{
+								"/opt/udm-run.sh",
+							},
+							VolumeMounts: []apiv1.VolumeMount{
+								{
+									Name:      "udm-config",
+									MountPath: "/opt",
+								},
+							},
+							Env: []apiv1.EnvVar{
+								{
+									Name:  "GRPC_GO_LOG_VERBOSITY_LEVEL",
+									Value: "99",
+								},
+								{
+									Name:  "GRPC_GO_LOG_SEVERITY_LEVEL",
+									Value: "info",
+								},
+								{
+									Name: "POD_IP",
+									ValueFrom: &apiv1.EnvVarSource{
+										FieldRef: &apiv1.ObjectFieldSelector{
+											FieldPath: "status.podIP",
+										},
+									},
+								},
+							},
+							Resources: apiv1.ResourceRequirements{
+								Requests: createResourceList("500m", "512Mi"),
+								Limits:   createResourceList("500m", "512Mi"),
+							},
+							ReadinessProbe: &apiv1.Probe{
+								ProbeHandler: apiv1.ProbeHandler{
+									TCPSocket: &apiv1.TCPSocketAction{
+										Port: intstr.FromInt(udmSbiPort),
+									},
+								},
+								InitialDelaySeconds: 5,
+								PeriodSeconds:       10,
+							},
+						},
+					},
+					Volumes: []apiv1.Volume{
+						{
+							Name: "udm-config",
+							VolumeSource: apiv1.VolumeSource{
+								ConfigMap: &apiv1.ConfigMapVolumeSource{
+									LocalObjectReference: apiv1.LocalObjectReference{
+										Name: controllers.GetNamespacedName(nfDeployment, udmConfigName),
+									},
+									DefaultMode: int32Ptr(0755), // Executable permission for scripts
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// buildService builds the Service exposing the UDM SBI endpoint
+func buildService(nfDeployment *nephiov1alpha1.NFDeployment) *apiv1.Service {
+	selector := map[string]string{
+		"app": controllers.GetNamespacedName(nfDeployment, "udm"),
+	}
+
+	return &apiv1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      controllers.GetNamespacedName(nfDeployment, udmServiceName),
+			Namespace: nfDeployment.Namespace,
+			Labels:    selector,
+		},
+		Spec: apiv1.ServiceSpec{
+			Selector: selector,
+			Ports: []apiv1.ServicePort{
+				{
+					Name:       udmSbiPortName,
+					Port:       udmSbiPort,
+					TargetPort: intstr.FromInt(udmSbiPort),
+					Protocol:   apiv1.ProtocolTCP,
+				},
+				{
+					Name:       udmPromPortName,
+					Port:       udmPromPort,
+					TargetPort: intstr.FromInt(udmPromPort),
+					Protocol:   apiv1.ProtocolTCP,
+				},
+			},
+		},
+	}
+}
+
+// Helper functions
+
+// int32Ptr returns a pointer to an int32
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+// createResourceList creates a resource list from CPU and memory values
+func createResourceList(cpu, memory string) apiv1.ResourceList {
+	return apiv1.ResourceList{
+		apiv1.ResourceCPU:    resource.MustParse(cpu),
+		apiv1.ResourceMemory: resource.MustParse(memory),
+	}
+}
+
+// generateUDMRunScript generates the UDM run script
+func generateUDMRunScript() string {
+	return `#!/bin/bash
+cd /free5gc
+./bin/udm -c /opt/udmcfg.yaml
+`
+}
+
+// generateUDMConfig generates the UDM configuration
+func generateUDMConfig(nfDeployment *nephiov1alpha1.NFDeployment, bindAddress string, identity controllers.NetworkIdentity) string {
+	serviceName := controllers.GetNamespacedName(nfDeployment, udmServiceName)
+
+	// The SUCI home network keys are the 3GPP TS 33.501 Annex C.4 test profiles used by SD-Core
+	return fmt.Sprintf(`info:
+  version: 1.0.0
+  description: UDM initial configuration
+
+configuration:
+  sbi:
+    scheme: http
+    registerIPv4: %s
+    bindingIPv4: "%s"
+    port: %d
+  serviceNameList:
+    - nudm-sdm
+    - nudm-uecm
+    - nudm-ueau
+    - nudm-ee
+    - nudm-pp
+  nrfUri: %s
+  plmnSupportList:
+%s  keys:
+    udmProfileAHNPublicKey: 5a8d38864820197c3394b92613b20b91633cbd897119273bf8e4a6f4eec0a650
+    udmProfileAHNPrivateKey: c53c22208b61860b06c62e5406a7b330c2b577aa5558981510d128247d38bd1d
+    udmProfileBHNPublicKey: 0472DA71976234CE833A6907425867B82E074D44EF907DFB4B3E21C1C2256EBCD15A7DED52FCBB097A4ED250E036C7B9C8C7004C4EEDC4F068CD7BF8D3F900E3B4
+    udmProfileBHNPrivateKey: F1AB1074477EBCC7F554EA1C5FC368B1616730155E0041AC447D6301975FECDA
+`, serviceName, bindAddress, udmSbiPort, controllers.GetNRFURI(nfDeployment), generatePlmnSupportList(identity.PLMNs))
+}
+
+// generatePlmnSupportList renders the PLMNs the UDM serves subscribers of
+func generatePlmnSupportList(plmns []controllers.PLMN) string {
+	var b strings.Builder
+	for _, plmn := range plmns {
+		fmt.Fprintf(&b, "    - plmnId:\n        mcc: \"%s\"\n        mnc: \"%s\"\n", plmn.MCC, plmn.MNC)
+	}
+	return b.String()
+}
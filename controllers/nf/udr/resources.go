@@ -0,0 +1,249 @@
+package udr
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/RohitRathore1/sdcore-operator/controllers"
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// UDR container names
+	udrContainerName = "udr"
+
+	// UDR config and service names
+	udrConfigName  = "udr-config"
+	udrServiceName = "udr-service"
+
+	// UDR port names
+	udrSbiPortName  = "sbi"
+	udrPromPortName = "prometheus"
+
+	// UDR port numbers
+	udrSbiPort  = 8080
+	udrPromPort = 9089
+)
+
+// buildConfigMap builds the ConfigMap holding the UDR configuration and run script
+func buildConfigMap(nfDeployment *nephiov1alpha1.NFDeployment, params *controllers.Parameters) (*apiv1.ConfigMap, error) {
+	identity, err := params.NetworkIdentity()
+	if err != nil {
+		return nil, err
+	}
+
+	bindAddress := controllers.SBIBindAddress(controllers.GetIPFamilies(nfDeployment, params))
+	udrConfig, err := controllers.MergeYAMLConfig(generateUDRConfig(nfDeployment, bindAddress, identity),
+		params.GetOverrides(controllers.NFTypeUDR))
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge UDR configuration overrides: %w", err)
+	}
+
+	return &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      controllers.GetNamespacedName(nfDeployment, udrConfigName),
+			Namespace: nfDeployment.Namespace,
+			Labels: map[string]string{
+				"app": controllers.GetNamespacedName(nfDeployment, "udr"),
+			},
+		},
+		Data: map[string]string{
+			"udr-run.sh":  generateUDRRunScript(),
+			"udrcfg.yaml": udrConfig,
+		},
+	}, nil
+}
+
+// buildDeployment builds the Deployment for the UDR
+func buildDeployment(nfDeployment *nephiov1alpha1.NFDeployment, params *controllers.Parameters) *appsv1.Deployment {
+	deploymentName := controllers.GetNamespacedName(nfDeployment, "udr")
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      deploymentName,
+			Namespace: nfDeployment.Namespace,
+			Labels: map[string]string{
+				"app": deploymentName,
+			},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: int32Ptr(1),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": deploymentName,
+				},
+			},
+			Template: apiv1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app": deploymentName,
+					},
+				},
+				Spec: apiv1.PodSpec{
+					Containers: []apiv1.Container{
+						{
+							Name:  udrContainerName,
+							Image: params.GetImage(controllers.ImageUDR),
+							Ports: []apiv1.ContainerPort{
+								{
+									Name:          udrSbiPortName,
+									ContainerPort: udrSbiPort,
+									Protocol:      apiv1.ProtocolTCP,
+								},
+								{
+									Name:          udrPromPortName,
+									ContainerPort: udrPromPort,
+									Protocol:      apiv1.ProtocolTCP,
+								},
+							},
+							Command: []string{
+								"/opt/udr-run.sh",
+							},
+							VolumeMounts: []apiv1.VolumeMount{
+								{
+									Name:      "udr-config",
+									MountPath: "/opt",
+								},
+							},
+							Env: []apiv1.EnvVar{
+								{
+									Name:  "GRPC_GO_LOG_VERBOSITY_LEVEL",
+									Value: "99",
+								},
+								{
+									Name:  "GRPC_GO_LOG_SEVERITY_LEVEL",
+									Value: "info",
+								},
+								{
+									Name: "POD_IP",
+									ValueFrom: &apiv1.EnvVarSource{
+										FieldRef: &apiv1.ObjectFieldSelector{
+											FieldPath: "status.podIP",
+										},
+									},
+								},
+							},
+							Resources: apiv1.ResourceRequirements{
+								Requests: createResourceList("500m", "512Mi"),
+								Limits:   createResourceList("500m", "512Mi"),
+							},
+							ReadinessProbe: &apiv1.Probe{
+								ProbeHandler: apiv1.ProbeHandler{
+									TCPSocket: &apiv1.TCPSocketAction{
+										Port: intstr.FromInt(udrSbiPort),
+									},
+								},
+								InitialDelaySeconds: 5,
+								PeriodSeconds:       10,
+							},
+						},
+					},
+					Volumes: []apiv1.Volume{
+						{
+							Name: "udr-config",
+							VolumeSource: apiv1.VolumeSource{
+								ConfigMap: &apiv1.ConfigMapVolumeSource{
+									LocalObjectReference: apiv1.LocalObjectReference{
+										Name: controllers.GetNamespacedName(nfDeployment, udrConfigName),
+									},
+									DefaultMode: int32Ptr(0755), // Executable permission for scripts
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// buildService builds the Service exposing the UDR SBI endpoint
+func buildService(nfDeployment *nephiov1alpha1.NFDeployment) *apiv1.Service {
+	selector := map[string]string{
+		"app": controllers.GetNamespacedName(nfDeployment, "udr"),
+	}
+
+	return &apiv1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      controllers.GetNamespacedName(nfDeployment, udrServiceName),
+			Namespace: nfDeployment.Namespace,
+			Labels:    selector,
+		},
+		Spec: apiv1.ServiceSpec{
+			Selector: selector,
+			Ports: []apiv1.ServicePort{
+				{
+					Name:       udrSbiPortName,
+					Port:       udrSbiPort,
+					TargetPort: intstr.FromInt(udrSbiPort),
+					Protocol:   apiv1.ProtocolTCP,
+				},
+				{
+					Name:       udrPromPortName,
+					Port:       udrPromPort,
+					TargetPort: intstr.FromInt(udrPromPort),
+					Protocol:   apiv1.ProtocolTCP,
+				},
+			},
+		},
+	}
+}
+
+// Helper functions
+
+// int32Ptr returns a pointer to an int32
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+// createResourceList creates a resource list from CPU and memory values
+func createResourceList(cpu, memory string) apiv1.ResourceList {
+	return apiv1.ResourceList{
+		apiv1.ResourceCPU:    resource.MustParse(cpu),
+		apiv1.ResourceMemory: resource.MustParse(memory),
+	}
+}
+
+// generateUDRRunScript generates the UDR run script
+func generateUDRRunScript() string {
+	return `#!/bin/bash
+cd /free5gc
+./bin/udr -c /opt/udrcfg.yaml
+`
+}
+
+// generateUDRConfig generates the UDR configuration
+func generateUDRConfig(nfDeployment *nephiov1alpha1.NFDeployment, bindAddress string, identity controllers.NetworkIdentity) string {
+	serviceName := controllers.GetNamespacedName(nfDeployment, udrServiceName)
+
+	return fmt.Sprintf(`info:
+  version: 1.0.0
+  description: UDR initial configuration
+
+configuration:
+  sbi:
+    scheme: http
+    registerIPv4: %s
+    bindingIPv4: "%s"
+    port: %d
+  mongodb:
+    name: %s
+    url: %s
+  nrfUri: %s
+  plmnSupportList:
+%s`, serviceName, bindAddress, udrSbiPort, controllers.DefaultMongoDBName, controllers.GetMongoDBURL(nfDeployment),
+		controllers.GetNRFURI(nfDeployment), generatePlmnSupportList(identity.PLMNs))
+}
+
+// generatePlmnSupportList renders the PLMNs the UDR holds subscription data for
+func generatePlmnSupportList(plmns []controllers.PLMN) string {
+	var b strings.Builder
+	for _, plmn := range plmns {
+		fmt.Fprintf(&b, "    - plmnId:\n        mcc: \"%s\"\n        mnc: \"%s\"\n", plmn.MCC, plmn.MNC)
+	}
+	return b.String()
+}
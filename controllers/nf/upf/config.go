@@ -0,0 +1,277 @@
+package upf
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+
+	"github.com/RohitRathore1/sdcore-operator/controllers"
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+)
+
+const (
+	// Names of the UPF interfaces in the NFDeployment spec
+	accessInterfaceName = "n3"
+	n4InterfaceName     = "n4"
+	coreInterfaceName   = "n6"
+
+	// defaultIfName is used for a dataplane port when its interface is not in the spec
+	defaultIfName = "eth0"
+	// defaultDNN is used when no data network is declared
+	defaultDNN = "internet"
+	// defaultThroughputBps is the slice rate limit used when no capacity is declared
+	defaultThroughputBps = 1000000000
+	// burstDurationSeconds is the time window the burst sizes are computed for
+	burstDurationSeconds = 0.1
+)
+
+// upfConfig is the BESS-UPF upf.jsonc configuration
+type upfConfig struct {
+	Mode                 string               `json:"mode"`
+	LogLevel             string               `json:"log_level"`
+	Workers              int                  `json:"workers"`
+	MaxSessions          int                  `json:"max_sessions"`
+	TableSizes           tableSizes           `json:"table_sizes"`
+	Access               ifaceConfig          `json:"access"`
+	Core                 ifaceConfig          `json:"core"`
+	MeasureUPF           bool                 `json:"measure_upf"`
+	MeasureFlow          bool                 `json:"measure_flow"`
+	EnableNotifyBess     bool                 `json:"enable_notify_bess"`
+	NotifySockAddr       string               `json:"notify_sockaddr"`
+	CPIface              cpIfaceConfig        `json:"cpiface"`
+	SliceRateLimitConfig sliceRateLimitConfig `json:"slice_rate_limit_config"`
+	QCIQoSConfig         []qciQoSConfig       `json:"qci_qos_config"`
+	Sim                  *simConfig           `json:"sim,omitempty"`
+}
+
+// tableSizes are the sizes of the BESS lookup tables
+type tableSizes struct {
+	PDRLookup        int `json:"pdrLookup"`
+	AppQERLookup     int `json:"appQERLookup"`
+	SessionQERLookup int `json:"sessionQERLookup"`
+	FARLookup        int `json:"farLookup"`
+}
+
+// ifaceConfig is a BESS dataplane port
+type ifaceConfig struct {
+	// IfName is the name of the pod interface the port is bound to
+	IfName string `json:"ifname"`
+	// IP is the address of the interface in CIDR notation
+	IP string `json:"ip,omitempty"`
+}
+
+// cpIfaceConfig is the configuration of the PFCP agent
+type cpIfaceConfig struct {
+	DNN      string `json:"dnn"`
+	Hostname string `json:"hostname"`
+	HTTPPort string `json:"http_port"`
+	UEIPPool string `json:"ue_ip_pool,omitempty"`
+}
+
+// sliceRateLimitConfig limits the traffic of the slice served by the UPF
+type sliceRateLimitConfig struct {
+	N6Bps        int64 `json:"n6_bps"`
+	N6BurstBytes int64 `json:"n6_burst_bytes"`
+	N3Bps        int64 `json:"n3_bps"`
+	N3BurstBytes int64 `json:"n3_burst_bytes"`
+}
+
+// qciQoSConfig is the QoS applied to a QCI
+type qciQoSConfig struct {
+	QCI             int `json:"qci"`
+	CBS             int `json:"cbs"`
+	EBS             int `json:"ebs"`
+	PBS             int `json:"pbs"`
+	BurstDurationMs int `json:"burst_duration_ms"`
+	Priority        int `json:"priority"`
+}
+
+// simConfig is the traffic generated by the UPF in sim mode
+type simConfig struct {
+	CoreIP      string `json:"core_ip"`
+	AccessIP    string `json:"access_ip"`
+	MaxSessions int    `json:"max_sessions"`
+	StartUEIP   string `json:"start_ue_ip"`
+	StartENBIP  string `json:"start_enb_ip"`
+	StartAUPFIP string `json:"start_aupf_ip"`
+	N6AppIP     string `json:"n6_app_ip"`
+	N9AppIP     string `json:"n9_app_ip"`
+	StartN3TEID string `json:"start_n3_teid"`
+	StartN9TEID string `json:"start_n9_teid"`
+	UplinkMBR   int64  `json:"uplink_mbr"`
+	DownlinkMBR int64  `json:"downlink_mbr"`
+	PktSize     int    `json:"pkt_size"`
+	TotalFlows  int    `json:"total_flows"`
+}
+
+// buildUPFConfig builds the UPF configuration from the interfaces and network instances of the
+// NFDeployment and the sizing derived from its capacity
+func buildUPFConfig(nfDeployment *nephiov1alpha1.NFDeployment, sizing upfSizing) (*upfConfig, error) {
+	// BESS ports only carry IPv4 GTP-U and N6 traffic
+	for _, name := range []string{accessInterfaceName, coreInterfaceName} {
+		iface := controllers.FindInterface(nfDeployment, name)
+		if iface != nil && iface.IPv6 != nil && iface.IPv4 == nil {
+			return nil, &controllers.InvalidSpecError{
+				Reason: controllers.ReasonInvalidAddress,
+				Err:    fmt.Errorf("interface %s: the UPF dataplane requires an IPv4 address", name),
+			}
+		}
+	}
+
+	dnn, ueIPPool := dataNetwork(nfDeployment)
+	// Without an N4 address pfcpiface listens on all addresses of the pod
+	var hostname string
+	n4Address, ok, err := controllers.InterfaceIP(nfDeployment, n4InterfaceName)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		hostname = n4Address.String()
+	}
+
+	config := &upfConfig{
+		Mode:             sizing.Mode,
+		LogLevel:         "info",
+		Workers:          sizing.Workers,
+		MaxSessions:      sizing.MaxSessions,
+		TableSizes:       sizing.TableSizes,
+		Access:           dataplaneIface(nfDeployment, accessInterfaceName),
+		Core:             dataplaneIface(nfDeployment, coreInterfaceName),
+		MeasureUPF:       true,
+		MeasureFlow:      false,
+		EnableNotifyBess: true,
+		NotifySockAddr:   "/pod-share/notifycp",
+		CPIface: cpIfaceConfig{
+			DNN:      dnn,
+			Hostname: hostname,
+			HTTPPort: "8080",
+			UEIPPool: ueIPPool,
+		},
+		SliceRateLimitConfig: sliceRateLimitConfig{
+			N6Bps:        sizing.DownlinkBps,
+			N6BurstBytes: burstBytes(sizing.DownlinkBps),
+			N3Bps:        sizing.UplinkBps,
+			N3BurstBytes: burstBytes(sizing.UplinkBps),
+		},
+		QCIQoSConfig: []qciQoSConfig{
+			{
+				QCI:             0,
+				CBS:             50000,
+				EBS:             50000,
+				PBS:             50000,
+				BurstDurationMs: 10,
+				Priority:        7,
+			},
+		},
+	}
+	if sizing.Mode == modeSim {
+		config.Sim = buildSimConfig(sizing, ueIPPool)
+	}
+	return config, nil
+}
+
+// buildSimConfig builds the simulated traffic for the sessions and UE pool of the UPF, the simulated
+// RAN and application endpoints keep the BESS-UPF sample addresses. The MBRs are in kbps.
+func buildSimConfig(sizing upfSizing, ueIPPool string) *simConfig {
+	config := &simConfig{
+		CoreIP:      "6.6.6.6",
+		AccessIP:    "198.18.0.1",
+		MaxSessions: sizing.MaxSessions,
+		StartUEIP:   "16.0.0.1",
+		StartENBIP:  "11.1.1.129",
+		StartAUPFIP: "13.1.1.199",
+		N6AppIP:     "6.6.6.6",
+		N9AppIP:     "13.2.1.1",
+		StartN3TEID: "0x30000000",
+		StartN9TEID: "0x90000000",
+		UplinkMBR:   sizing.UplinkBps / 1000,
+		DownlinkMBR: sizing.DownlinkBps / 1000,
+		PktSize:     128,
+	}
+	if _, pool, err := net.ParseCIDR(ueIPPool); err == nil {
+		// The first UE address follows the network address of the pool
+		ip := pool.IP.To4()
+		if ip != nil {
+			config.StartUEIP = net.IPv4(ip[0], ip[1], ip[2], ip[3]+1).String()
+		}
+	}
+	return config
+}
+
+// generateUPFConfig renders the UPF configuration as upf.jsonc
+func generateUPFConfig(nfDeployment *nephiov1alpha1.NFDeployment, sizing upfSizing) (string, error) {
+	config, err := buildUPFConfig(nfDeployment, sizing)
+	if err != nil {
+		return "", err
+	}
+	rendered, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(rendered), nil
+}
+
+// dataplaneIface returns the BESS port for an interface, falling back to the pod default interface
+func dataplaneIface(nfDeployment *nephiov1alpha1.NFDeployment, name string) ifaceConfig {
+	iface := controllers.FindInterface(nfDeployment, name)
+	if iface == nil {
+		return ifaceConfig{IfName: defaultIfName}
+	}
+	config := ifaceConfig{IfName: iface.Name}
+	if iface.IPv4 != nil {
+		config.IP = iface.IPv4.Address
+	}
+	return config
+}
+
+// dataNetwork returns the name and first UE pool of the data network served on the N6 network instance
+func dataNetwork(nfDeployment *nephiov1alpha1.NFDeployment) (string, string) {
+	var fallback *nephiov1alpha1.DataNetwork
+	for _, networkInstance := range nfDeployment.Spec.NetworkInstances {
+		for i := range networkInstance.DataNetworks {
+			dataNetwork := &networkInstance.DataNetworks[i]
+			if dataNetwork.Name == nil {
+				continue
+			}
+			for _, name := range networkInstance.Interfaces {
+				if name == coreInterfaceName {
+					return *dataNetwork.Name, firstPool(dataNetwork)
+				}
+			}
+			if fallback == nil {
+				fallback = dataNetwork
+			}
+		}
+	}
+	if fallback != nil {
+		return *fallback.Name, firstPool(fallback)
+	}
+	return defaultDNN, ""
+}
+
+// firstPool returns the first UE pool prefix of a data network
+func firstPool(dataNetwork *nephiov1alpha1.DataNetwork) string {
+	if len(dataNetwork.Pool) == 0 {
+		return ""
+	}
+	return dataNetwork.Pool[0].Prefix
+}
+
+// throughput returns the uplink and downlink throughput in bits per second from the capacity
+func throughput(nfDeployment *nephiov1alpha1.NFDeployment) (int64, int64) {
+	uplink, downlink := int64(defaultThroughputBps), int64(defaultThroughputBps)
+	if capacity := nfDeployment.Spec.Capacity; capacity != nil {
+		if value := capacity.MaxUplinkThroughput.Value(); value > 0 {
+			uplink = value
+		}
+		if value := capacity.MaxDownlinkThroughput.Value(); value > 0 {
+			downlink = value
+		}
+	}
+	return uplink, downlink
+}
+
+// burstBytes returns the burst size in bytes allowed for a rate in bits per second
+func burstBytes(bps int64) int64 {
+	return int64(float64(bps) / 8 * burstDurationSeconds)
+}
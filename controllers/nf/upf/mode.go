@@ -0,0 +1,140 @@
+package upf
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/RohitRathore1/sdcore-operator/controllers"
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// BESS dataplane modes
+const (
+	modeAFPacket = "af_packet"
+	modeAFXDP    = "af_xdp"
+	modeDPDK     = "dpdk"
+	modeCNDP     = "cndp"
+	modeSim      = "sim"
+
+	// defaultMode runs the dataplane on af_packet sockets without hugepages
+	defaultMode = modeAFPacket
+
+	// modeLabel records the dataplane mode on the UPF pod template
+	modeLabel = "nf.sdcore.io/upf-mode"
+)
+
+// modeProfile describes what a dataplane mode requires from the bessd container
+type modeProfile struct {
+	// hugePages is true if the mode allocates its packet buffers and tables from hugepages
+	hugePages bool
+	// sriov is true if the N3 and N6 ports must be SR-IOV VFs allocated by the device plugin
+	sriov bool
+	// deviceResource is true if the N3 and N6 ports are allocated from the AF_XDP device plugin
+	deviceResource bool
+	// privileged is true if bessd loads eBPF programs and needs a privileged container
+	privileged bool
+	// capabilities are added to bessd when it is not privileged
+	capabilities []apiv1.Capability
+}
+
+// modeProfiles holds the profile of each supported dataplane mode
+var modeProfiles = map[string]modeProfile{
+	modeAFPacket: {
+		capabilities: []apiv1.Capability{"IPC_LOCK", "CAP_SYS_NICE"},
+	},
+	modeAFXDP: {
+		privileged: true,
+	},
+	modeDPDK: {
+		hugePages:    true,
+		sriov:        true,
+		capabilities: []apiv1.Capability{"IPC_LOCK", "CAP_SYS_NICE", "NET_ADMIN"},
+	},
+	modeCNDP: {
+		hugePages:      true,
+		deviceResource: true,
+		privileged:     true,
+	},
+	modeSim: {
+		capabilities: []apiv1.Capability{"IPC_LOCK", "CAP_SYS_NICE"},
+	},
+}
+
+// reasonInvalidMode is the InvalidSpec reason for a dataplane mode that is unknown or whose attachments
+// and parameters are missing
+const reasonInvalidMode = "InvalidDataplaneMode"
+
+// resolveMode returns the dataplane mode from the nf.sdcore.io/upf-mode annotation, the UPF parameters
+// or af_packet by default. A mode the interfaces are not attached for is reported as an InvalidSpecError.
+func resolveMode(nfDeployment *nephiov1alpha1.NFDeployment, params *controllers.Parameters) (string, error) {
+	mode, errs := checkMode(nfDeployment, params)
+	if len(errs) > 0 {
+		return "", &controllers.InvalidSpecError{
+			Reason: reasonInvalidMode,
+			Err:    errs.ToAggregate(),
+		}
+	}
+	return mode, nil
+}
+
+// checkMode returns the selected dataplane mode and the errors of the mode, its interfaces must be
+// attached the way the mode requires
+func checkMode(nfDeployment *nephiov1alpha1.NFDeployment, params *controllers.Parameters) (string, field.ErrorList) {
+	parametersPath := field.NewPath("spec", "parametersRefs")
+	mode, modePath := defaultMode, parametersPath
+	if value := params.GetUPF().Mode; value != "" {
+		mode = value
+	}
+	if value, ok := nfDeployment.Annotations[controllers.UPFModeKey]; ok && value != "" {
+		mode, modePath = value, field.NewPath("metadata", "annotations").Key(controllers.UPFModeKey)
+	}
+	mode = strings.ToLower(mode)
+
+	profile, ok := modeProfiles[mode]
+	if !ok {
+		return "", field.ErrorList{field.NotSupported(modePath, mode,
+			[]string{modeAFPacket, modeAFXDP, modeDPDK, modeCNDP, modeSim})}
+	}
+	var errs field.ErrorList
+	if profile.sriov {
+		for _, name := range []string{accessInterfaceName, coreInterfaceName} {
+			if cniType := params.GetAttachment(name).CNIType; cniType != controllers.CNITypeSRIOV {
+				errs = append(errs, field.Invalid(parametersPath, cniType,
+					fmt.Sprintf("UPF dataplane mode %s requires the %s interface to use an %s attachment",
+						mode, name, controllers.CNITypeSRIOV)))
+			}
+		}
+	}
+	if profile.deviceResource && params.GetUPF().DeviceResource == "" {
+		errs = append(errs, field.Required(parametersPath,
+			fmt.Sprintf("UPF dataplane mode %s requires the upf deviceResource parameter", mode)))
+	}
+	return mode, errs
+}
+
+// usesHugePages returns true if the BESS dataplane mode allocates its memory from hugepages
+func usesHugePages(mode string) bool {
+	return modeProfiles[mode].hugePages
+}
+
+// bessdSecurityContext returns the bessd security context for the dataplane mode
+func bessdSecurityContext(mode string) *apiv1.SecurityContext {
+	profile := modeProfiles[mode]
+	if profile.privileged {
+		privileged := true
+		return &apiv1.SecurityContext{Privileged: &privileged}
+	}
+	return &apiv1.SecurityContext{
+		Capabilities: &apiv1.Capabilities{
+			Add: profile.capabilities,
+		},
+	}
+}
+
+// bessdArgs returns the bessd command line, hugepages are allocated per socket in MB
+func bessdArgs(sizing upfSizing) string {
+	hugePagesMB := sizing.HugePages.Value() / (1024 * 1024)
+	return fmt.Sprintf("bessd -m %d -f --grpc_url=0.0.0.0:10514", hugePagesMB)
+}
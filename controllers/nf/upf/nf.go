@@ -0,0 +1,78 @@
+package upf
+
+import (
+	"time"
+
+	"github.com/RohitRathore1/sdcore-operator/controllers"
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func init() {
+	controllers.Register(networkFunction{})
+}
+
+// networkFunction implements controllers.NetworkFunction for the UPF
+type networkFunction struct{}
+
+// Type returns the UPF NF type
+func (networkFunction) Type() controllers.NFType {
+	return controllers.NFTypeUPF
+}
+
+// MatchesProvider returns true for the upf.sdcore.io provider
+func (networkFunction) MatchesProvider(provider string) bool {
+	return controllers.MatchesSDCoreProvider(controllers.NFTypeUPF, provider)
+}
+
+// DrainTimeout returns the time the UPF teardown waits for its SMFs to release their PFCP associations
+func (networkFunction) DrainTimeout() time.Duration {
+	return 2 * time.Minute
+}
+
+// ValidateSpec requires the N3, N4 and N6 interfaces, the N3 and N6 dataplane ports with an IPv4 address
+func (networkFunction) ValidateSpec(nfDeployment *nephiov1alpha1.NFDeployment) field.ErrorList {
+	errs := controllers.RequireInterfaces(nfDeployment, accessInterfaceName, n4InterfaceName, coreInterfaceName)
+	interfacesPath := field.NewPath("spec", "interfaces")
+	for i, iface := range nfDeployment.Spec.Interfaces {
+		if iface.Name != accessInterfaceName && iface.Name != coreInterfaceName {
+			continue
+		}
+		if iface.IPv6 != nil && iface.IPv4 == nil {
+			errs = append(errs, field.Required(interfacesPath.Index(i).Child("ipv4"),
+				"the UPF dataplane requires an IPv4 address"))
+		}
+	}
+	return errs
+}
+
+// ValidateParameters rejects a dataplane mode that is unknown or whose attachments and parameters are missing
+func (networkFunction) ValidateParameters(nfDeployment *nephiov1alpha1.NFDeployment, params *controllers.Parameters) field.ErrorList {
+	_, errs := checkMode(nfDeployment, params)
+	return errs
+}
+
+// DefaultSpec declares the capacity the UPF is sized with when the NFDeployment leaves it out
+func (networkFunction) DefaultSpec(nfDeployment *nephiov1alpha1.NFDeployment) {
+	defaultCapacity(nfDeployment)
+}
+
+// BuildResources returns the ConfigMap, Deployment and Service of the UPF
+func (networkFunction) BuildResources(nfDeployment *nephiov1alpha1.NFDeployment, params *controllers.Parameters) ([]client.Object, error) {
+	mode, err := resolveMode(nfDeployment, params)
+	if err != nil {
+		return nil, err
+	}
+	sizing := computeSizing(nfDeployment, mode)
+	configMap, err := buildConfigMap(nfDeployment, params, sizing)
+	if err != nil {
+		return nil, err
+	}
+
+	return []client.Object{
+		configMap,
+		buildDeployment(nfDeployment, params, sizing),
+		buildService(nfDeployment),
+	}, nil
+}
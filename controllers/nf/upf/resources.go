@@ -1,7 +1,8 @@
 package upf
 
 import (
-	"context"
+	"fmt"
+	"strings"
 
 	"github.com/RohitRathore1/sdcore-operator/controllers"
 	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
@@ -10,15 +11,10 @@ import (
 	resource "k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
-	ctrl "sigs.k8s.io/controller-runtime"
-	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 // Constants for the UPF deployment
 const (
-	upfBessImageName       = "omecproject/upf-epc-bess:rel-2.0.1"
-	upfPfcpifaceImageName  = "omecproject/upf-epc-pfcpiface:rel-2.0.1"
-	upfToolsImageName      = "omecproject/pod-init:rel-1.1.2"
 	upfContainerName       = "upf"
 	upfConfigName          = "upf-config"
 	upfServiceName         = "upf-service"
@@ -26,137 +22,90 @@ const (
 	routectlContainerName  = "routectl"
 	webContainerName       = "web"
 	pfcpAgentContainerName = "pfcp-agent"
+	hugePagesResource      = apiv1.ResourceHugePagesPrefix + "1Gi"
+	hugePagesMedium        = apiv1.StorageMediumHugePagesPrefix + "1Gi"
+
+	// dataplaneProgrammedFile is created in the bessd container by the post-start script once the
+	// BESS pipeline is loaded, bessd is only ready after that
+	dataplaneProgrammedFile = "/tmp/dataplane-programmed"
 )
 
-// reconcileConfigMap ensures the ConfigMap for the UPF deployment exists and is up to date
-func (r *UPFDeploymentReconciler) reconcileConfigMap(ctx context.Context, nfDeployment *nephiov1alpha1.NFDeployment) error {
-	log := ctrl.LoggerFrom(ctx)
+// buildConfigMap builds the ConfigMap holding the UPF configuration and BESS post-start script
+func buildConfigMap(nfDeployment *nephiov1alpha1.NFDeployment, params *controllers.Parameters, sizing upfSizing) (*apiv1.ConfigMap, error) {
+	// Generate UPF configuration based on NFDeployment spec and parameters
+	upfConfig, err := generateUPFConfig(nfDeployment, sizing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate UPF configuration: %w", err)
+	}
+	upfConfig, err = controllers.MergeJSONConfig(upfConfig, params.GetOverrides(controllers.NFTypeUPF))
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge UPF configuration overrides: %w", err)
+	}
 
-	// Create a ConfigMap for UPF configuration
-	configMapName := controllers.GetNamespacedName(nfDeployment, upfConfigName)
-	configMap := &apiv1.ConfigMap{
+	return &apiv1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      configMapName,
+			Name:      controllers.GetNamespacedName(nfDeployment, upfConfigName),
 			Namespace: nfDeployment.Namespace,
 		},
-	}
-
-	// Create or update the ConfigMap
-	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, configMap, func() error {
-		// Set the owner reference so the ConfigMap is automatically cleaned up
-		if err := ctrl.SetControllerReference(nfDeployment, configMap, r.Scheme); err != nil {
-			return err
-		}
-
-		// Generate UPF configuration based on NFDeployment spec
-		configMap.Data = map[string]string{
-			"upf.jsonc":          generateUPFConfig(nfDeployment),
+		Data: map[string]string{
+			"upf.jsonc":          upfConfig,
 			"bessd-poststart.sh": generateBESSPostStartScript(),
-		}
-
-		return nil
-	})
-
-	if err != nil {
-		return err
-	}
-
-	log.Info("ConfigMap reconciled", "operation", op)
-	return nil
+		},
+	}, nil
 }
 
-// reconcileDeployment ensures the Deployment for the UPF exists and is up to date
-func (r *UPFDeploymentReconciler) reconcileDeployment(ctx context.Context, nfDeployment *nephiov1alpha1.NFDeployment) error {
-	log := ctrl.LoggerFrom(ctx)
-
-	// Create a Deployment for UPF
-	deploymentName := controllers.GetNamespacedName(nfDeployment, "upf")
+// buildDeployment builds the Deployment for the UPF
+func buildDeployment(nfDeployment *nephiov1alpha1.NFDeployment, params *controllers.Parameters, sizing upfSizing) *appsv1.Deployment {
 	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      deploymentName,
+			Name:      controllers.GetNamespacedName(nfDeployment, "upf"),
 			Namespace: nfDeployment.Namespace,
 		},
 	}
 
-	// Create or update the Deployment
-	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, deployment, func() error {
-		// Set the owner reference so the Deployment is automatically cleaned up
-		if err := ctrl.SetControllerReference(nfDeployment, deployment, r.Scheme); err != nil {
-			return err
-		}
-
-		// Configure deployment spec
-		configureDeploymentSpec(deployment, nfDeployment)
+	// Configure deployment spec
+	configureDeploymentSpec(deployment, nfDeployment, params, sizing)
 
-		return nil
-	})
-
-	if err != nil {
-		return err
-	}
-
-	log.Info("Deployment reconciled", "operation", op)
-	return nil
+	return deployment
 }
 
-// reconcileService ensures the Service for the UPF exists and is up to date
-func (r *UPFDeploymentReconciler) reconcileService(ctx context.Context, nfDeployment *nephiov1alpha1.NFDeployment) error {
-	log := ctrl.LoggerFrom(ctx)
-
-	// Create a Service for UPF
-	serviceName := controllers.GetNamespacedName(nfDeployment, upfServiceName)
-	service := &apiv1.Service{
+// buildService builds the Service exposing the UPF PFCP, BESS web and metrics endpoints
+func buildService(nfDeployment *nephiov1alpha1.NFDeployment) *apiv1.Service {
+	return &apiv1.Service{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      serviceName,
+			Name:      controllers.GetNamespacedName(nfDeployment, upfServiceName),
 			Namespace: nfDeployment.Namespace,
 		},
-	}
-
-	// Create or update the Service
-	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, service, func() error {
-		// Set the owner reference so the Service is automatically cleaned up
-		if err := ctrl.SetControllerReference(nfDeployment, service, r.Scheme); err != nil {
-			return err
-		}
-
-		// Configure service spec
-		service.Spec.Selector = map[string]string{
-			"app": controllers.GetNamespacedName(nfDeployment, "upf"),
-		}
-		service.Spec.Ports = []apiv1.ServicePort{
-			{
-				Name:       "pfcp",
-				Protocol:   apiv1.ProtocolUDP,
-				Port:       8805,
-				TargetPort: intstr.FromInt(8805),
-			},
-			{
-				Name:       "bess-web",
-				Protocol:   apiv1.ProtocolTCP,
-				Port:       8000,
-				TargetPort: intstr.FromInt(8000),
+		Spec: apiv1.ServiceSpec{
+			Selector: map[string]string{
+				"app": controllers.GetNamespacedName(nfDeployment, "upf"),
 			},
-			{
-				Name:       "prometheus",
-				Protocol:   apiv1.ProtocolTCP,
-				Port:       8080,
-				TargetPort: intstr.FromInt(8080),
+			Ports: []apiv1.ServicePort{
+				{
+					Name:       "pfcp",
+					Protocol:   apiv1.ProtocolUDP,
+					Port:       8805,
+					TargetPort: intstr.FromInt(8805),
+				},
+				{
+					Name:       "bess-web",
+					Protocol:   apiv1.ProtocolTCP,
+					Port:       8000,
+					TargetPort: intstr.FromInt(8000),
+				},
+				{
+					Name:       "prometheus",
+					Protocol:   apiv1.ProtocolTCP,
+					Port:       8080,
+					TargetPort: intstr.FromInt(8080),
+				},
 			},
-		}
-
-		return nil
-	})
-
-	if err != nil {
-		return err
+		},
 	}
-
-	log.Info("Service reconciled", "operation", op)
-	return nil
 }
 
 // configureDeploymentSpec configures the deployment spec for the UPF
-func configureDeploymentSpec(deployment *appsv1.Deployment, nfDeployment *nephiov1alpha1.NFDeployment) {
+func configureDeploymentSpec(deployment *appsv1.Deployment, nfDeployment *nephiov1alpha1.NFDeployment, params *controllers.Parameters, sizing upfSizing) {
 	deployment.Spec.Replicas = func() *int32 { i := int32(1); return &i }()
 
 	// Set labels and selector
@@ -169,11 +118,18 @@ func configureDeploymentSpec(deployment *appsv1.Deployment, nfDeployment *nephio
 		MatchLabels: labels,
 	}
 
-	deployment.Spec.Template.ObjectMeta.Labels = labels
+	// The template also records the dataplane mode, the selector stays on the app label
+	deployment.Spec.Template.ObjectMeta.Labels = map[string]string{
+		"app":     appLabel,
+		modeLabel: sizing.Mode,
+	}
 
 	// ConfigMap name
 	configMapName := controllers.GetNamespacedName(nfDeployment, upfConfigName)
 
+	// bessd, routectl, web and bess-init share the BESS image
+	bessImage := params.GetImage(controllers.ImageUPFBESS)
+
 	// Configure shared process namespace
 	shareProcessNamespace := true
 	deployment.Spec.Template.Spec.ShareProcessNamespace = &shareProcessNamespace
@@ -182,15 +138,9 @@ func configureDeploymentSpec(deployment *appsv1.Deployment, nfDeployment *nephio
 	deployment.Spec.Template.Spec.InitContainers = []apiv1.Container{
 		{
 			Name:    "bess-init",
-			Image:   upfBessImageName,
+			Image:   bessImage,
 			Command: []string{"sh", "-xec"},
-			Args: []string{
-				`echo "Skipping network setup for local testing";
-				echo "In a real environment, we would run:";
-				echo "ip route replace 192.168.251.0/24 via 192.168.252.1";
-				echo "ip route replace default via 192.168.250.1 metric 110";
-				echo "iptables -I OUTPUT -p icmp --icmp-type port-unreachable -j DROP";`,
-			},
+			Args:    []string{generateBESSInitScript(nfDeployment)},
 			SecurityContext: &apiv1.SecurityContext{
 				Capabilities: &apiv1.Capabilities{
 					Add: []apiv1.Capability{
@@ -214,20 +164,13 @@ func configureDeploymentSpec(deployment *appsv1.Deployment, nfDeployment *nephio
 	// Configure container spec
 	deployment.Spec.Template.Spec.Containers = []apiv1.Container{
 		{
-			Name:  bessdContainerName,
-			Image: upfBessImageName,
-			SecurityContext: &apiv1.SecurityContext{
-				Capabilities: &apiv1.Capabilities{
-					Add: []apiv1.Capability{
-						"IPC_LOCK",
-						"CAP_SYS_NICE",
-					},
-				},
-			},
-			Command: []string{"/bin/bash", "-xc"},
-			Args:    []string{"bessd -m 0 -f --grpc_url=0.0.0.0:10514"},
-			Stdin:   true,
-			TTY:     true,
+			Name:            bessdContainerName,
+			Image:           bessImage,
+			SecurityContext: bessdSecurityContext(sizing.Mode),
+			Command:         []string{"/bin/bash", "-xc"},
+			Args:            []string{bessdArgs(sizing)},
+			Stdin:           true,
+			TTY:             true,
 			Lifecycle: &apiv1.Lifecycle{
 				PostStart: &apiv1.LifecycleHandler{
 					Exec: &apiv1.ExecAction{
@@ -236,14 +179,8 @@ func configureDeploymentSpec(deployment *appsv1.Deployment, nfDeployment *nephio
 				},
 			},
 			Resources: apiv1.ResourceRequirements{
-				Requests: apiv1.ResourceList{
-					apiv1.ResourceCPU:    resource.MustParse("2"),
-					apiv1.ResourceMemory: resource.MustParse("2Gi"),
-				},
-				Limits: apiv1.ResourceList{
-					apiv1.ResourceCPU:    resource.MustParse("2"),
-					apiv1.ResourceMemory: resource.MustParse("2Gi"),
-				},
+				Requests: bessdResourceList(sizing, params),
+				Limits:   bessdResourceList(sizing, params),
 			},
 			Env: []apiv1.EnvVar{
 				{
@@ -270,10 +207,18 @@ func configureDeploymentSpec(deployment *appsv1.Deployment, nfDeployment *nephio
 				InitialDelaySeconds: 15,
 				PeriodSeconds:       20,
 			},
+			ReadinessProbe: &apiv1.Probe{
+				ProbeHandler: apiv1.ProbeHandler{
+					Exec: &apiv1.ExecAction{
+						Command: []string{"test", "-f", dataplaneProgrammedFile},
+					},
+				},
+				PeriodSeconds: 10,
+			},
 		},
 		{
 			Name:  routectlContainerName,
-			Image: upfBessImageName,
+			Image: bessImage,
 			Env: []apiv1.EnvVar{
 				{
 					Name:  "PYTHONUNBUFFERED",
@@ -281,7 +226,11 @@ func configureDeploymentSpec(deployment *appsv1.Deployment, nfDeployment *nephio
 				},
 			},
 			Command: []string{"/opt/bess/bessctl/conf/route_control.py"},
-			Args:    []string{"-i", "eth0", "eth0"},
+			Args: []string{
+				"-i",
+				dataplaneIface(nfDeployment, accessInterfaceName).IfName,
+				dataplaneIface(nfDeployment, coreInterfaceName).IfName,
+			},
 			Resources: apiv1.ResourceRequirements{
 				Requests: apiv1.ResourceList{
 					apiv1.ResourceCPU:    resource.MustParse("256m"),
@@ -295,7 +244,7 @@ func configureDeploymentSpec(deployment *appsv1.Deployment, nfDeployment *nephio
 		},
 		{
 			Name:    webContainerName,
-			Image:   upfBessImageName,
+			Image:   bessImage,
 			Command: []string{"/bin/bash", "-xc", "bessctl http 0.0.0.0 8000"},
 			Resources: apiv1.ResourceRequirements{
 				Requests: apiv1.ResourceList{
@@ -310,9 +259,18 @@ func configureDeploymentSpec(deployment *appsv1.Deployment, nfDeployment *nephio
 		},
 		{
 			Name:    pfcpAgentContainerName,
-			Image:   upfPfcpifaceImageName,
+			Image:   params.GetImage(controllers.ImageUPFPFCPIface),
 			Command: []string{"pfcpiface"},
 			Args:    []string{"-config", "/tmp/conf/upf.jsonc"},
+			// PFCP runs over UDP, the agent HTTP endpoint tells whether it is up
+			ReadinessProbe: &apiv1.Probe{
+				ProbeHandler: apiv1.ProbeHandler{
+					TCPSocket: &apiv1.TCPSocketAction{
+						Port: intstr.FromInt(8080),
+					},
+				},
+				PeriodSeconds: 10,
+			},
 			Resources: apiv1.ResourceRequirements{
 				Requests: apiv1.ResourceList{
 					apiv1.ResourceCPU:    resource.MustParse("256m"),
@@ -356,71 +314,73 @@ func configureDeploymentSpec(deployment *appsv1.Deployment, nfDeployment *nephio
 			},
 		},
 	}
+
+	// Back the DPDK memory with hugepages in the modes using them
+	if !sizing.HugePages.IsZero() {
+		deployment.Spec.Template.Spec.Volumes = append(deployment.Spec.Template.Spec.Volumes, apiv1.Volume{
+			Name: "hugepages",
+			VolumeSource: apiv1.VolumeSource{
+				EmptyDir: &apiv1.EmptyDirVolumeSource{
+					Medium: hugePagesMedium,
+				},
+			},
+		})
+		bessd := &deployment.Spec.Template.Spec.Containers[0]
+		bessd.VolumeMounts = append(bessd.VolumeMounts, apiv1.VolumeMount{
+			Name:      "hugepages",
+			MountPath: "/dev/hugepages",
+		})
+	}
 }
 
-// generateUPFConfig generates the UPF configuration based on the NFDeployment spec
-func generateUPFConfig(nfDeployment *nephiov1alpha1.NFDeployment) string {
-	// This is a simplified configuration based on the BESS-UPF Helm chart
-	// In a real implementation, this would parse the NFDeployment spec more thoroughly
+// bessdResourceList returns the bessd CPU, memory and hugepages from the UPF sizing and, in cndp mode,
+// the N3 and N6 ports from the AF_XDP device plugin
+func bessdResourceList(sizing upfSizing, params *controllers.Parameters) apiv1.ResourceList {
+	resources := apiv1.ResourceList{
+		apiv1.ResourceCPU:    sizing.CPU,
+		apiv1.ResourceMemory: sizing.Memory,
+	}
+	if !sizing.HugePages.IsZero() {
+		resources[hugePagesResource] = sizing.HugePages
+	}
+	if modeProfiles[sizing.Mode].deviceResource {
+		resources[apiv1.ResourceName(params.GetUPF().DeviceResource)] = resource.MustParse("2")
+	}
+	return resources
+}
 
-	// Modified configuration for local testing that doesn't rely on network interfaces
-	return `{
-  "mode": "af_packet",
-  "log_level": "info",
-  "workers": 1,
-  "max_sessions": 50000,
-  "table_sizes": {
-    "pdrLookup": 50000,
-    "appQERLookup": 200000,
-    "sessionQERLookup": 100000,
-    "farLookup": 150000
-  },
-  "access": {
-    "ifname": "eth0"
-  },
-  "core": {
-    "ifname": "eth0"
-  },
-  "measure_upf": true,
-  "measure_flow": false,
-  "enable_notify_bess": true,
-  "notify_sockaddr": "/pod-share/notifycp",
-  "cpiface": {
-    "dnn": "internet",
-    "hostname": "",
-    "http_port": "8080"
-  },
-  "slice_rate_limit_config": {
-    "n6_bps": 1000000000,
-    "n6_burst_bytes": 12500000,
-    "n3_bps": 1000000000,
-    "n3_burst_bytes": 12500000
-  },
-  "qci_qos_config": [
-    {
-      "qci": 0,
-      "cbs": 50000,
-      "ebs": 50000,
-      "pbs": 50000,
-      "burst_duration_ms": 10,
-      "priority": 7
-    }
-  ]
-}`
+// generateBESSInitScript generates the network setup of the UPF pod. Routes to the N3 peers are installed
+// by the network attachment, the default route goes through the N6 gateway towards the data network.
+func generateBESSInitScript(nfDeployment *nephiov1alpha1.NFDeployment) string {
+	var b strings.Builder
+	if iface := controllers.FindInterface(nfDeployment, coreInterfaceName); iface != nil && iface.IPv4 != nil && iface.IPv4.Gateway != nil {
+		fmt.Fprintf(&b, "ip route replace default via %s metric 110\n", *iface.IPv4.Gateway)
+	}
+	// Do not answer GTP-U and PFCP packets the kernel does not own with port unreachable
+	b.WriteString("iptables -I OUTPUT -p icmp --icmp-type port-unreachable -j DROP\n")
+	return b.String()
 }
 
-// generateBESSPostStartScript generates the post-start script for BESS
+// generateBESSPostStartScript generates the post-start script loading the BESS pipeline. A failure is
+// written to the termination log and fails the hook, so that the kubelet restarts bessd and the error
+// shows in the container status and the FailedPostStartHook event.
 func generateBESSPostStartScript() string {
-	return `#!/bin/bash
+	return fmt.Sprintf(`#!/bin/bash
 set -x
 
 echo "Waiting for BESS to start..."
-sleep 5
+for i in $(seq 1 30); do
+  bessctl show version && break
+  sleep 1
+done
 
 echo "Running BESS configuration..."
-bessctl run /opt/bess/bessctl/conf/up4.bess -- $CONF_FILE || {
-  echo "Error running BESS configuration, but continuing anyway for testing purposes"
-  exit 0
-}
-`
+if ! output=$(bessctl run /opt/bess/bessctl/conf/up4.bess -- $CONF_FILE 2>&1); then
+  echo "$output"
+  echo "BESS pipeline configuration failed: $(echo "$output" | tail -n 5)" | tee /dev/termination-log
+  exit 1
+fi
+echo "$output"
+touch %s
+`, dataplaneProgrammedFile)
 }
@@ -0,0 +1,138 @@
+package upf
+
+import (
+	"fmt"
+
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+	nephioreqv1alpha1 "github.com/nephio-project/api/nf_requirements/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// defaultMaxSessions is the session capacity used when none is declared
+	defaultMaxSessions = 50000
+
+	// workerThroughputBps is the combined uplink and downlink throughput handled by one BESS worker
+	workerThroughputBps = 10000000000
+	// maxWorkers is the largest number of BESS workers the UPF is sized with
+	maxWorkers = 8
+
+	// sessionsPerMemoryGi is the number of sessions accounted per Gi of bessd memory
+	sessionsPerMemoryGi = 50000
+	// sessionsPerHugePageGi is the number of sessions accounted per Gi of hugepages for the lookup tables
+	sessionsPerHugePageGi = 100000
+)
+
+// upfSizing is the UPF dimensioning derived from the NFDeployment capacity
+type upfSizing struct {
+	// Mode is the BESS dataplane mode the sizing applies to
+	Mode string
+	// Workers is the number of BESS worker cores
+	Workers int
+	// MaxSessions is the number of PFCP sessions the tables are sized for
+	MaxSessions int
+	// TableSizes are the BESS lookup table sizes
+	TableSizes tableSizes
+	// UplinkBps and DownlinkBps are the N3 and N6 slice rate limits in bits per second
+	UplinkBps   int64
+	DownlinkBps int64
+	// CPU and Memory are the bessd container requests and limits
+	CPU    resource.Quantity
+	Memory resource.Quantity
+	// HugePages is the bessd hugepages-1Gi request, zero when the dataplane mode does not use hugepages
+	HugePages resource.Quantity
+}
+
+// computeSizing converts the NFDeployment capacity into BESS workers, table sizes, rate limits and
+// bessd resources. Without a capacity it yields the sizing of a 50000 sessions, 1Gbps UPF.
+func computeSizing(nfDeployment *nephiov1alpha1.NFDeployment, mode string) upfSizing {
+	uplinkBps, downlinkBps := throughput(nfDeployment)
+	maxSessions := sessions(nfDeployment)
+
+	// One worker per 10Gbps of combined throughput
+	workers := int(ceilDiv(uplinkBps+downlinkBps, workerThroughputBps))
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > maxWorkers {
+		workers = maxWorkers
+	}
+
+	// Each session installs uplink and downlink PDRs, FARs and QERs, the ratios match the
+	// BESS-UPF defaults for 50000 sessions
+	sizes := tableSizes{
+		PDRLookup:        maxSessions,
+		AppQERLookup:     4 * maxSessions,
+		SessionQERLookup: 2 * maxSessions,
+		FARLookup:        3 * maxSessions,
+	}
+
+	// A dedicated core per worker plus one for the BESS control thread and gRPC server
+	cpu := *resource.NewQuantity(int64(workers+1), resource.DecimalSI)
+	memoryGi := 1 + ceilDiv(int64(maxSessions), sessionsPerMemoryGi)
+	memory := resource.MustParse(fmt.Sprintf("%dGi", memoryGi))
+
+	hugePages := resource.MustParse("0")
+	if usesHugePages(mode) {
+		// Packet buffers per worker plus the lookup tables
+		hugePagesGi := int64(workers) + ceilDiv(int64(maxSessions), sessionsPerHugePageGi)
+		hugePages = resource.MustParse(fmt.Sprintf("%dGi", hugePagesGi))
+	}
+
+	return upfSizing{
+		Mode:        mode,
+		Workers:     workers,
+		MaxSessions: maxSessions,
+		TableSizes:  sizes,
+		UplinkBps:   uplinkBps,
+		DownlinkBps: downlinkBps,
+		CPU:         cpu,
+		Memory:      memory,
+		HugePages:   hugePages,
+	}
+}
+
+// String summarizes the sizing for the NFDeployment status
+func (s upfSizing) String() string {
+	return fmt.Sprintf("mode=%s workers=%d maxSessions=%d uplink=%s downlink=%s cpu=%s memory=%s hugepages=%s",
+		s.Mode, s.Workers, s.MaxSessions,
+		resource.NewQuantity(s.UplinkBps, resource.DecimalSI), resource.NewQuantity(s.DownlinkBps, resource.DecimalSI),
+		s.CPU.String(), s.Memory.String(), s.HugePages.String())
+}
+
+// sessions returns the number of sessions from the capacity, MaxSessions and MaxSubscribers are in 1000s.
+// Without MaxSessions one session per subscriber is assumed.
+func sessions(nfDeployment *nephiov1alpha1.NFDeployment) int {
+	maxSessions := defaultMaxSessions
+	if capacity := nfDeployment.Spec.Capacity; capacity != nil {
+		if capacity.MaxSessions > 0 {
+			maxSessions = capacity.MaxSessions * 1000
+		} else if capacity.MaxSubscribers > 0 {
+			maxSessions = capacity.MaxSubscribers * 1000
+		}
+	}
+	return maxSessions
+}
+
+// ceilDiv returns a divided by b rounded up
+func ceilDiv(a, b int64) int64 {
+	return (a + b - 1) / b
+}
+
+// defaultCapacity sets the throughput and sessions left out of the capacity to the values the UPF is sized
+// with without them. Sessions are left to be derived from MaxSubscribers when it is set.
+func defaultCapacity(nfDeployment *nephiov1alpha1.NFDeployment) {
+	if nfDeployment.Spec.Capacity == nil {
+		nfDeployment.Spec.Capacity = new(nephioreqv1alpha1.CapacitySpec)
+	}
+	capacity := nfDeployment.Spec.Capacity
+	if capacity.MaxUplinkThroughput.IsZero() {
+		capacity.MaxUplinkThroughput = *resource.NewQuantity(defaultThroughputBps, resource.DecimalSI)
+	}
+	if capacity.MaxDownlinkThroughput.IsZero() {
+		capacity.MaxDownlinkThroughput = *resource.NewQuantity(defaultThroughputBps, resource.DecimalSI)
+	}
+	if capacity.MaxSessions == 0 && capacity.MaxSubscribers == 0 {
+		capacity.MaxSessions = defaultMaxSessions / 1000
+	}
+}
@@ -1,85 +1,155 @@
 package upf
 
 import (
+	"fmt"
+	"strings"
+
+	"github.com/RohitRathore1/sdcore-operator/controllers/nfstatus"
 	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
 	appsv1 "k8s.io/api/apps/v1"
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-func createNfDeploymentStatus(deployment *appsv1.Deployment, nfDeployment *nephiov1alpha1.NFDeployment) (nephiov1alpha1.NFDeploymentStatus, bool) {
-	observedGeneration := int32(deployment.ObjectMeta.Generation)
-	status := nfDeployment.Status
-	changed := false
+const (
+	// conditionSized reports the UPF sizing computed from the NFDeployment capacity
+	conditionSized = "Sized"
 
-	if status.ObservedGeneration != observedGeneration {
-		status.ObservedGeneration = observedGeneration
-		changed = true
-	}
+	// conditionDataplaneProgrammed reports whether BESS runs with its pipeline loaded and its routes synced
+	conditionDataplaneProgrammed = "DataplaneProgrammed"
 
-	if status.Conditions == nil {
-		status.Conditions = []metav1.Condition{}
-	}
+	// conditionPFCPAgentUp reports whether the PFCP agent serving the SMFs is up
+	conditionPFCPAgentUp = "PFCPAgentUp"
+)
 
-	// Check for Available condition
-	availableCondition := metav1.Condition{
-		Type:               string(nephiov1alpha1.Available),
-		Status:             metav1.ConditionFalse,
-		Reason:             "DeploymentUnavailable",
-		Message:            "Deployment is not available",
-		LastTransitionTime: metav1.Now(),
+// upfComponent is a part of the UPF pod reported by a condition of its own
+type upfComponent struct {
+	conditionType string
+	containers    []string
+	// notReadyReason is the reason of a running container that is not ready
+	notReadyReason string
+}
+
+// upfComponents are the components of the UPF pod reported on the NFDeployment status
+var upfComponents = []upfComponent{
+	{
+		conditionType:  conditionDataplaneProgrammed,
+		containers:     []string{bessdContainerName, routectlContainerName},
+		notReadyReason: "PipelineNotLoaded",
+	},
+	{
+		conditionType:  conditionPFCPAgentUp,
+		containers:     []string{pfcpAgentContainerName},
+		notReadyReason: "AgentNotListening",
+	},
+}
+
+// ReportStatus reports the sizing applied to the UPF Deployment and the health of the UPF components
+// from the container statuses of its pods
+func (networkFunction) ReportStatus(status *nephiov1alpha1.NFDeploymentStatus, nfDeployment *nephiov1alpha1.NFDeployment,
+	deployment *appsv1.Deployment, pods []apiv1.Pod) {
+	reason := "DefaultCapacity"
+	if nfDeployment.Spec.Capacity != nil {
+		reason = "CapacityApplied"
 	}
+	nfstatus.Set(status, nfDeployment, conditionSized, metav1.ConditionTrue, reason,
+		computeSizing(nfDeployment, deploymentMode(deployment)).String())
 
-	for _, condition := range deployment.Status.Conditions {
-		if condition.Type == appsv1.DeploymentAvailable && condition.Status == apiv1.ConditionTrue {
-			availableCondition.Status = metav1.ConditionTrue
-			availableCondition.Reason = "DeploymentAvailable"
-			availableCondition.Message = "Deployment is available"
-			break
+	var running []apiv1.Pod
+	for _, pod := range pods {
+		if pod.DeletionTimestamp == nil && pod.Status.Phase != apiv1.PodSucceeded && pod.Status.Phase != apiv1.PodFailed {
+			running = append(running, pod)
 		}
 	}
+	for _, component := range upfComponents {
+		reportComponent(status, nfDeployment, component, running)
+	}
+}
 
-	// Update the Available condition if it changed
-	availableChanged := updateCondition(&status.Conditions, availableCondition)
-	changed = changed || availableChanged
-
-	// Check for Ready condition
-	readyCondition := metav1.Condition{
-		Type:               string(nephiov1alpha1.Ready),
-		Status:             metav1.ConditionFalse,
-		Reason:             "NotReady",
-		Message:            "UPF is not ready",
-		LastTransitionTime: metav1.Now(),
+// reportComponent sets the condition of a UPF component, true when its containers are ready in every pod.
+// The message of a false condition explains the first container that is not ready.
+func reportComponent(status *nephiov1alpha1.NFDeploymentStatus, nfDeployment *nephiov1alpha1.NFDeployment,
+	component upfComponent, pods []apiv1.Pod) {
+	if len(pods) == 0 {
+		nfstatus.Set(status, nfDeployment, component.conditionType, metav1.ConditionFalse, "PodNotFound", "No UPF pod is running")
+		return
 	}
 
-	if availableCondition.Status == metav1.ConditionTrue &&
-		deployment.Status.ReadyReplicas == deployment.Status.Replicas {
-		readyCondition.Status = metav1.ConditionTrue
-		readyCondition.Reason = "Ready"
-		readyCondition.Message = "UPF is ready"
+	var restarts []string
+	for _, pod := range pods {
+		for _, name := range component.containers {
+			container := findContainerStatus(pod.Status.ContainerStatuses, name)
+			if container == nil {
+				nfstatus.Set(status, nfDeployment, component.conditionType, metav1.ConditionFalse, "ContainerNotStarted",
+					fmt.Sprintf("%s/%s: not started", pod.Name, name))
+				return
+			}
+			if !container.Ready {
+				reason := component.notReadyReason
+				if container.State.Waiting != nil && container.State.Waiting.Reason != "" {
+					reason = container.State.Waiting.Reason
+				}
+				nfstatus.Set(status, nfDeployment, component.conditionType, metav1.ConditionFalse, reason,
+					describeContainer(pod.Name, container))
+				return
+			}
+			if container.RestartCount > 0 {
+				restarts = append(restarts, describeContainer(pod.Name, container))
+			}
+		}
 	}
 
-	// Update the Ready condition if it changed
-	readyChanged := updateCondition(&status.Conditions, readyCondition)
-	changed = changed || readyChanged
+	message := fmt.Sprintf("%s ready", strings.Join(component.containers, " and "))
+	if len(restarts) > 0 {
+		message = fmt.Sprintf("%s; %s", message, strings.Join(restarts, "; "))
+	}
+	nfstatus.Set(status, nfDeployment, component.conditionType, metav1.ConditionTrue, "ContainersReady", message)
+}
 
-	return status, changed
+// findContainerStatus returns the status of a container of a pod
+func findContainerStatus(statuses []apiv1.ContainerStatus, name string) *apiv1.ContainerStatus {
+	for i := range statuses {
+		if statuses[i].Name == name {
+			return &statuses[i]
+		}
+	}
+	return nil
 }
 
-// updateCondition updates the condition in the list of conditions or adds it if not present
-// Returns true if the condition was changed
-func updateCondition(conditions *[]metav1.Condition, condition metav1.Condition) bool {
-	for i, c := range *conditions {
-		if c.Type == condition.Type {
-			if c.Status != condition.Status || c.Reason != condition.Reason || c.Message != condition.Message {
-				(*conditions)[i] = condition
-				return true
-			}
-			return false
+// describeContainer describes the state of a container, its restarts and why it last terminated,
+// e.g. "upf-abc/bessd: waiting (CrashLoopBackOff), restarted 3 times, last terminated with exit code 1: ..."
+func describeContainer(podName string, container *apiv1.ContainerStatus) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s/%s: ", podName, container.Name)
+	switch state := container.State; {
+	case state.Waiting != nil:
+		fmt.Fprintf(&b, "waiting (%s)", state.Waiting.Reason)
+	case state.Terminated != nil:
+		fmt.Fprintf(&b, "terminated (%s)", state.Terminated.Reason)
+	case container.Ready:
+		b.WriteString("ready")
+	default:
+		b.WriteString("running, not ready")
+	}
+	if container.RestartCount > 0 {
+		fmt.Fprintf(&b, ", restarted %d times", container.RestartCount)
+	}
+	if last := container.LastTerminationState.Terminated; last != nil {
+		fmt.Fprintf(&b, ", last terminated with exit code %d", last.ExitCode)
+		if last.Reason != "" {
+			fmt.Fprintf(&b, " (%s)", last.Reason)
+		}
+		if message := strings.TrimSpace(last.Message); message != "" {
+			fmt.Fprintf(&b, ": %s", message)
 		}
 	}
+	return b.String()
+}
 
-	// Condition not found, add it
-	*conditions = append(*conditions, condition)
-	return true
+// deploymentMode returns the dataplane mode recorded on the UPF pod template
+func deploymentMode(deployment *appsv1.Deployment) string {
+	if mode, ok := deployment.Spec.Template.Labels[modeLabel]; ok {
+		return mode
+	}
+	return defaultMode
 }
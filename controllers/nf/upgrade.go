@@ -0,0 +1,366 @@
+package nf
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/RohitRathore1/sdcore-operator/controllers"
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// upgradeRecordKey is the key of the upgrade record in its ConfigMap
+	upgradeRecordKey = "upgrade.json"
+
+	// upgradeHistoryLength is the number of upgrades kept in the history
+	upgradeHistoryLength = 10
+
+	// upgradePollInterval is the interval the progress of an upgrade is checked at
+	upgradePollInterval = 10 * time.Second
+)
+
+// Results of upgrades recorded in the history
+const (
+	upgradeSucceeded  = "Succeeded"
+	upgradeRolledBack = "RolledBack"
+	upgradeRejected   = "Rejected"
+)
+
+// revision is a version of a network function: its release, the images of its containers and the hash of
+// its configuration
+type revision struct {
+	Release    string            `json:"release"`
+	Images     map[string]string `json:"images"`
+	ConfigHash string            `json:"configHash"`
+}
+
+// version describes the release, configuration hash and images of the revision,
+// e.g. "1.6 config 3f9a0c1d2e4b5a69 (amf=omecproject/5gc-amf:rel-1.6.4)"
+func (r *revision) version() string {
+	containers := make([]string, 0, len(r.Images))
+	for container := range r.Images {
+		containers = append(containers, container)
+	}
+	sort.Strings(containers)
+	images := make([]string, 0, len(containers))
+	for _, container := range containers {
+		images = append(images, fmt.Sprintf("%s=%s", container, r.Images[container]))
+	}
+	return fmt.Sprintf("%s config %s (%s)", r.Release, r.ConfigHash, strings.Join(images, ", "))
+}
+
+// upgradeEvent is an upgrade in the history
+type upgradeEvent struct {
+	Time    metav1.Time `json:"time"`
+	From    string      `json:"from"`
+	To      string      `json:"to"`
+	Result  string      `json:"result"`
+	Message string      `json:"message,omitempty"`
+}
+
+// upgradeRecord is the upgrade state of an NFDeployment, kept in a ConfigMap it owns
+type upgradeRecord struct {
+	// Current is the last revision that rolled out
+	Current *revision `json:"current"`
+	// Target is the revision being rolled out since Started
+	Target  *revision    `json:"target,omitempty"`
+	Started *metav1.Time `json:"started,omitempty"`
+	// FailedImages are the images of the last rolled back revision, they are not retried
+	FailedImages map[string]string `json:"failedImages,omitempty"`
+	History      []upgradeEvent    `json:"history,omitempty"`
+}
+
+// record appends an upgrade to the history
+func (u *upgradeRecord) record(event upgradeEvent) {
+	u.History = append(u.History, event)
+	if len(u.History) > upgradeHistoryLength {
+		u.History = u.History[len(u.History)-upgradeHistoryLength:]
+	}
+}
+
+// upgradePlan is the outcome of planning the version of an NFDeployment: the resources to apply and
+// the Upgraded condition to report, if it changes
+type upgradePlan struct {
+	objects   []client.Object
+	condition *metav1.Condition
+	requeue   bool
+}
+
+// planUpgrade rolls out a change of the images of the network function as an upgrade. The upgrade must pass
+// the pre-flight checks and become available within the progress deadline, otherwise the previous images
+// are restored. Only the images are pinned: configuration and peer changes keep rolling out on the running
+// images, so the peers recorded on the Deployment are those its configuration is built from. The state and
+// history of upgrades are kept in a ConfigMap.
+func (r *NFDeploymentReconciler) planUpgrade(ctx context.Context, nfDeployment *nephiov1alpha1.NFDeployment,
+	nfType controllers.NFType, params *controllers.Parameters, objects []client.Object) (upgradePlan, error) {
+	log := ctrl.LoggerFrom(ctx)
+	plan := upgradePlan{objects: objects}
+	deployment := findDeployment(objects)
+	if deployment == nil {
+		return plan, nil
+	}
+	settings := params.GetUpgrade()
+	setRollout(deployment, settings)
+	deadline := settings.GetProgressDeadlineSeconds()
+
+	recordMap, upgrade, err := r.getUpgradeRecord(ctx, nfDeployment, nfType)
+	if err != nil {
+		return plan, err
+	}
+	desired := newRevision(params.GetRelease(), objects)
+	now := metav1.Now()
+
+	switch {
+	case upgrade.Current == nil:
+		// First rollout of the network function
+		upgrade.Current = desired
+
+	case upgrade.Target != nil && sameImages(desired.Images, upgrade.Target.Images):
+		existing := new(appsv1.Deployment)
+		err := r.Client.Get(ctx, client.ObjectKeyFromObject(deployment), existing)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return plan, err
+		}
+		done, failure := rolloutProgress(existing, upgrade.Target)
+		if failure == "" && now.Sub(upgrade.Started.Time) > time.Duration(deadline)*time.Second {
+			failure = fmt.Sprintf("not available within %ds", deadline)
+		}
+		switch {
+		case done:
+			log.Info("Upgrade succeeded", "version", desired.version())
+			upgrade.record(upgradeEvent{Time: now, From: upgrade.Current.version(), To: desired.version(), Result: upgradeSucceeded})
+			plan.condition = upgradeCondition(metav1.ConditionTrue, upgradeSucceeded,
+				fmt.Sprintf("Upgraded from %s to %s", upgrade.Current.version(), desired.version()))
+			upgrade.Current, upgrade.Target, upgrade.Started = desired, nil, nil
+		case failure != "":
+			log.Info("Upgrade failed, rolling back", "version", desired.version(), "reason", failure)
+			message := fmt.Sprintf("Upgrade to %s rolled back to %s: %s", desired.version(), upgrade.Current.version(), failure)
+			upgrade.record(upgradeEvent{Time: now, From: upgrade.Current.version(), To: desired.version(),
+				Result: upgradeRolledBack, Message: failure})
+			plan.condition = upgradeCondition(metav1.ConditionFalse, upgradeRolledBack, message)
+			upgrade.FailedImages, upgrade.Target, upgrade.Started = desired.Images, nil, nil
+			plan.objects = pinImages(objects, upgrade.Current, desired)
+		default:
+			upgrade.Target = desired
+			plan.requeue = true
+		}
+
+	case sameImages(desired.Images, upgrade.Current.Images):
+		// A configuration change, or an upgrade reverted before it completed
+		if upgrade.Target != nil {
+			plan.condition = upgradeCondition(metav1.ConditionTrue, "UpgradeCancelled",
+				fmt.Sprintf("Upgrade to %s cancelled, running %s", upgrade.Target.version(), desired.version()))
+		}
+		upgrade.Current, upgrade.Target, upgrade.Started = desired, nil, nil
+
+	case sameImages(desired.Images, upgrade.FailedImages):
+		// Keep the rolled back images until other images are selected
+		plan.objects = pinImages(objects, upgrade.Current, desired)
+
+	default:
+		if err := preflightUpgrade(upgrade.Current, desired); err != nil {
+			log.Info("Upgrade rejected", "version", desired.version(), "reason", err.Error())
+			last := len(upgrade.History) - 1
+			if last < 0 || upgrade.History[last].Result != upgradeRejected || upgrade.History[last].To != desired.version() {
+				upgrade.record(upgradeEvent{Time: now, From: upgrade.Current.version(), To: desired.version(),
+					Result: upgradeRejected, Message: err.Error()})
+			}
+			plan.condition = upgradeCondition(metav1.ConditionFalse, "PreflightFailed",
+				fmt.Sprintf("Upgrade to %s rejected: %s", desired.version(), err))
+			plan.objects = pinImages(objects, upgrade.Current, desired)
+			break
+		}
+		log.Info("Upgrading", "from", upgrade.Current.version(), "to", desired.version())
+		plan.condition = upgradeCondition(metav1.ConditionUnknown, "UpgradeInProgress",
+			fmt.Sprintf("Upgrading from %s to %s", upgrade.Current.version(), desired.version()))
+		upgrade.Target, upgrade.Started, upgrade.FailedImages = desired, &now, nil
+		plan.requeue = true
+	}
+
+	data, err := json.Marshal(upgrade)
+	if err != nil {
+		return plan, err
+	}
+	recordMap.Data = map[string]string{upgradeRecordKey: string(data)}
+	plan.objects = append(plan.objects, recordMap)
+	return plan, nil
+}
+
+// getUpgradeRecord returns the ConfigMap holding the upgrade record of the NFDeployment and the record,
+// empty before the first rollout
+func (r *NFDeploymentReconciler) getUpgradeRecord(ctx context.Context, nfDeployment *nephiov1alpha1.NFDeployment,
+	nfType controllers.NFType) (*apiv1.ConfigMap, *upgradeRecord, error) {
+	key := types.NamespacedName{
+		Namespace: nfDeployment.Namespace,
+		Name:      controllers.GetNamespacedName(nfDeployment, string(nfType)+"-upgrade"),
+	}
+	upgrade := new(upgradeRecord)
+	existing := new(apiv1.ConfigMap)
+	err := r.Client.Get(ctx, key, existing)
+	switch {
+	case k8serrors.IsNotFound(err):
+	case err != nil:
+		return nil, nil, err
+	default:
+		if data, ok := existing.Data[upgradeRecordKey]; ok {
+			if err := json.Unmarshal([]byte(data), upgrade); err != nil {
+				return nil, nil, fmt.Errorf("invalid upgrade record %s: %w", key, err)
+			}
+		}
+	}
+	return &apiv1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}}, upgrade, nil
+}
+
+// preflightUpgrade checks the target revision can replace the current one in place: its release and the
+// release of the image of every container must be upgradable from the running ones
+func preflightUpgrade(current, target *revision) error {
+	if err := controllers.CheckUpgrade(current.Release, target.Release); err != nil {
+		return err
+	}
+	for container, image := range target.Images {
+		currentImage, ok := current.Images[container]
+		if !ok {
+			return fmt.Errorf("container %s is not part of the running version", container)
+		}
+		// Component overrides may select the image of another release than the selected one
+		if err := controllers.CheckImageUpgrade(currentImage, image); err != nil {
+			return fmt.Errorf("container %s: %w", container, err)
+		}
+	}
+	return nil
+}
+
+// rolloutProgress returns whether the Deployment runs the target revision and is available, or why the
+// rollout failed
+func rolloutProgress(deployment *appsv1.Deployment, target *revision) (bool, string) {
+	if !sameImages(deploymentImages(deployment), target.Images) {
+		return false, ""
+	}
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Status == apiv1.ConditionFalse {
+			return false, condition.Message
+		}
+	}
+	if !controllers.RolledOut(deployment) {
+		return false, ""
+	}
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentAvailable && condition.Status == apiv1.ConditionTrue {
+			return deployment.Status.ReadyReplicas == deployment.Status.Replicas, ""
+		}
+	}
+	return false, ""
+}
+
+// newRevision returns the revision of the resources of a network function
+func newRevision(release string, objects []client.Object) *revision {
+	rev := &revision{
+		Release:    release,
+		ConfigHash: controllers.ConfigHash(objects),
+	}
+	if deployment := findDeployment(objects); deployment != nil {
+		rev.Images = deploymentImages(deployment)
+	}
+	return rev
+}
+
+// pinImages sets the images of the running revision on the Deployment among the resources, their
+// configuration is left as desired and becomes the configuration of the running revision
+func pinImages(objects []client.Object, current, desired *revision) []client.Object {
+	if deployment := findDeployment(objects); deployment != nil {
+		spec := &deployment.Spec.Template.Spec
+		for _, containers := range [][]apiv1.Container{spec.InitContainers, spec.Containers} {
+			for i := range containers {
+				if image, ok := current.Images[containers[i].Name]; ok {
+					containers[i].Image = image
+				}
+			}
+		}
+	}
+	current.ConfigHash = desired.ConfigHash
+	return objects
+}
+
+// deploymentImages returns the images of the containers of a Deployment by container name
+func deploymentImages(deployment *appsv1.Deployment) map[string]string {
+	images := map[string]string{}
+	spec := deployment.Spec.Template.Spec
+	for _, containers := range [][]apiv1.Container{spec.InitContainers, spec.Containers} {
+		for _, container := range containers {
+			images[container.Name] = container.Image
+		}
+	}
+	return images
+}
+
+// sameImages returns true if both sets of images are equal
+func sameImages(a, b map[string]string) bool {
+	if len(a) == 0 || len(a) != len(b) {
+		return false
+	}
+	for container, image := range a {
+		if b[container] != image {
+			return false
+		}
+	}
+	return true
+}
+
+// setRollout sets the rollout strategy and progress deadline of the Deployment
+func setRollout(deployment *appsv1.Deployment, settings controllers.UpgradeParameters) {
+	setStrategy(deployment, settings.Strategy)
+	deadline := settings.GetProgressDeadlineSeconds()
+	deployment.Spec.ProgressDeadlineSeconds = &deadline
+}
+
+// setStrategy sets the rollout strategy of the Deployment. The rolling update parameters are set explicitly with
+// the Kubernetes defaults so that they are owned by the operator and removed when switching to Recreate.
+// Without a strategy, pods with network attachments are recreated: a surge pod would start with the static
+// addresses and SR-IOV VFs the running pod holds.
+func setStrategy(deployment *appsv1.Deployment, strategy appsv1.DeploymentStrategyType) {
+	if strategy == "" {
+		if _, ok := deployment.Spec.Template.Annotations[controllers.NetworksAnnotation]; ok {
+			strategy = appsv1.RecreateDeploymentStrategyType
+		}
+	}
+	if strategy == appsv1.RecreateDeploymentStrategyType {
+		deployment.Spec.Strategy = appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}
+		return
+	}
+	maxUnavailable := intstr.FromString("25%")
+	maxSurge := intstr.FromString("25%")
+	deployment.Spec.Strategy = appsv1.DeploymentStrategy{
+		Type: appsv1.RollingUpdateDeploymentStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateDeployment{
+			MaxUnavailable: &maxUnavailable,
+			MaxSurge:       &maxSurge,
+		},
+	}
+}
+
+// findDeployment returns the Deployment among the resources of a network function
+func findDeployment(objects []client.Object) *appsv1.Deployment {
+	for _, object := range objects {
+		if deployment, ok := object.(*appsv1.Deployment); ok {
+			return deployment
+		}
+	}
+	return nil
+}
+
+// upgradeCondition returns the Upgraded condition
+func upgradeCondition(status metav1.ConditionStatus, reason, message string) *metav1.Condition {
+	return &metav1.Condition{Status: status, Reason: reason, Message: message}
+}
@@ -0,0 +1,282 @@
+// Package nfstatus computes the NFDeployment status conditions reported for every network function
+package nfstatus
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/RohitRathore1/sdcore-operator/controllers"
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Condition types reported on every NFDeployment. Reconciling, Stalled, Available and Ready are the
+// Nephio condition types, Peered and DependencyReady report the peers the NF is built from.
+const (
+	ConditionReconciling     = string(nephiov1alpha1.Reconciling)
+	ConditionStalled         = string(nephiov1alpha1.Stalled)
+	ConditionAvailable       = string(nephiov1alpha1.Available)
+	ConditionReady           = string(nephiov1alpha1.Ready)
+	ConditionPeered          = "Peered"
+	ConditionDependencyReady = "DependencyReady"
+
+	// ConditionImagesResolved reports the image digests the pods of the NF run
+	ConditionImagesResolved = "ImagesResolved"
+
+	// ConditionUpgraded reports the outcome of the last version upgrade
+	ConditionUpgraded = "Upgraded"
+
+	// ConditionTerminating reports the progress of the ordered teardown of a deleted NFDeployment
+	ConditionTerminating = "Terminating"
+
+	// ConditionResourceConflict reports the resources of the NF left to the owner controlling them
+	ConditionResourceConflict = "ResourceConflict"
+)
+
+// Set sets a condition on the status for the current generation of the NFDeployment. The transition
+// time only changes with the condition status, so setting an unchanged condition leaves the status equal.
+func Set(status *nephiov1alpha1.NFDeploymentStatus, nfDeployment *nephiov1alpha1.NFDeployment,
+	conditionType string, conditionStatus metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             conditionStatus,
+		ObservedGeneration: nfDeployment.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// SetStalled marks the NFDeployment as stalled, e.g. on an invalid spec, and no longer reconciling
+func SetStalled(status *nephiov1alpha1.NFDeploymentStatus, nfDeployment *nephiov1alpha1.NFDeployment, reason, message string) {
+	status.ObservedGeneration = int32(nfDeployment.Generation)
+	Set(status, nfDeployment, ConditionStalled, metav1.ConditionTrue, reason, message)
+	Set(status, nfDeployment, ConditionReconciling, metav1.ConditionFalse, reason, "Reconciliation is stalled")
+}
+
+// Compute returns the status of the NFDeployment of the network function from its Deployment, nil when not
+// created yet, the pods of the Deployment and its peer NFDeployments by NF type. Conditions set by others,
+// e.g. InvalidSpec, are kept.
+func Compute(nf controllers.NetworkFunction, nfDeployment *nephiov1alpha1.NFDeployment, deployment *appsv1.Deployment,
+	pods []apiv1.Pod, peers map[controllers.NFType][]nephiov1alpha1.NFDeployment) nephiov1alpha1.NFDeploymentStatus {
+	status := *nfDeployment.Status.DeepCopy()
+	status.ObservedGeneration = int32(nfDeployment.Generation)
+
+	var peerTypes []controllers.NFType
+	if dependent, ok := nf.(controllers.PeerDependent); ok {
+		peerTypes = dependent.PeerTypes()
+	}
+
+	available := setDeploymentConditions(&status, nfDeployment, deployment)
+	peered := setPeered(&status, nfDeployment, peerTypes, peers)
+	dependencyReady := setDependencyReady(&status, nfDeployment, peerTypes, peers)
+
+	switch {
+	case !available:
+		Set(&status, nfDeployment, ConditionReady, metav1.ConditionFalse, "NotAvailable", "The NF is not available")
+	case !peered:
+		Set(&status, nfDeployment, ConditionReady, metav1.ConditionFalse, "NotPeered", "The NF has no peers")
+	case !dependencyReady:
+		Set(&status, nfDeployment, ConditionReady, metav1.ConditionFalse, "DependencyNotReady", "The peers of the NF are not ready")
+	default:
+		Set(&status, nfDeployment, ConditionReady, metav1.ConditionTrue, "Ready", "The NF is ready to serve requests")
+	}
+
+	if deployment != nil {
+		setImagesResolved(&status, nfDeployment, deployment, pods)
+	}
+	if reporter, ok := nf.(controllers.StatusReporter); ok && deployment != nil {
+		reporter.ReportStatus(&status, nfDeployment, deployment, pods)
+	}
+	return status
+}
+
+// setDeploymentConditions sets the Reconciling, Stalled, Available and ConfigApplied conditions from the
+// Deployment and returns true if all its pods are ready
+func setDeploymentConditions(status *nephiov1alpha1.NFDeploymentStatus, nfDeployment *nephiov1alpha1.NFDeployment,
+	deployment *appsv1.Deployment) bool {
+	if deployment == nil {
+		Set(status, nfDeployment, ConditionReconciling, metav1.ConditionTrue, "DeploymentPending", "The Deployment is not created yet")
+		Set(status, nfDeployment, ConditionStalled, metav1.ConditionFalse, "DeploymentPending", "The Deployment is not created yet")
+		Set(status, nfDeployment, ConditionAvailable, metav1.ConditionFalse, "DeploymentPending", "The Deployment is not created yet")
+		return false
+	}
+
+	hash := deployment.Spec.Template.Annotations[controllers.ConfigHashAnnotation]
+	if controllers.RolledOut(deployment) {
+		Set(status, nfDeployment, ConditionReconciling, metav1.ConditionFalse, "RolloutComplete", "All pods run the current pod template")
+		Set(status, nfDeployment, controllers.ConditionConfigApplied, metav1.ConditionTrue, "RolloutComplete",
+			fmt.Sprintf("Active configuration %s", hash))
+	} else {
+		Set(status, nfDeployment, ConditionReconciling, metav1.ConditionTrue, "RolloutInProgress", "The pods are being rolled out")
+		Set(status, nfDeployment, controllers.ConditionConfigApplied, metav1.ConditionFalse, "RolloutInProgress",
+			fmt.Sprintf("Rolling out configuration %s", hash))
+	}
+
+	// A Deployment past its progress deadline or failing to create pods does not recover by itself
+	stalled, available := false, false
+	for _, condition := range deployment.Status.Conditions {
+		switch {
+		case stalled:
+		case condition.Type == appsv1.DeploymentProgressing && condition.Status == apiv1.ConditionFalse,
+			condition.Type == appsv1.DeploymentReplicaFailure && condition.Status == apiv1.ConditionTrue:
+			Set(status, nfDeployment, ConditionStalled, metav1.ConditionTrue, condition.Reason, condition.Message)
+			stalled = true
+		}
+		if condition.Type == appsv1.DeploymentAvailable && condition.Status == apiv1.ConditionTrue {
+			available = true
+		}
+	}
+	if !stalled {
+		Set(status, nfDeployment, ConditionStalled, metav1.ConditionFalse, "Progressing", "The Deployment is progressing")
+	}
+	if available {
+		Set(status, nfDeployment, ConditionAvailable, metav1.ConditionTrue, "DeploymentAvailable", "The Deployment is available")
+	} else {
+		Set(status, nfDeployment, ConditionAvailable, metav1.ConditionFalse, "DeploymentUnavailable", "The Deployment is not available")
+	}
+	return available && deployment.Status.ReadyReplicas >= desiredReplicas(deployment)
+}
+
+// setPeered sets the Peered condition, true when there is a peer of every peer type or none is needed
+func setPeered(status *nephiov1alpha1.NFDeploymentStatus, nfDeployment *nephiov1alpha1.NFDeployment,
+	peerTypes []controllers.NFType, peers map[controllers.NFType][]nephiov1alpha1.NFDeployment) bool {
+	if len(peerTypes) == 0 {
+		Set(status, nfDeployment, ConditionPeered, metav1.ConditionTrue, "NoPeersRequired", "The NF is not built from peers")
+		return true
+	}
+	var found, missing []string
+	for _, peerType := range peerTypes {
+		if count := len(peers[peerType]); count > 0 {
+			found = append(found, fmt.Sprintf("%d %s", count, strings.ToUpper(string(peerType))))
+		} else {
+			missing = append(missing, strings.ToUpper(string(peerType)))
+		}
+	}
+	if len(missing) > 0 {
+		Set(status, nfDeployment, ConditionPeered, metav1.ConditionFalse, "PeersNotFound",
+			fmt.Sprintf("No %s peer found", strings.Join(missing, " or ")))
+		return false
+	}
+	Set(status, nfDeployment, ConditionPeered, metav1.ConditionTrue, "PeersFound",
+		fmt.Sprintf("Peered with %s", strings.Join(found, ", ")))
+	return true
+}
+
+// setDependencyReady sets the DependencyReady condition, true when a peer of every peer type is ready.
+// The message lists the peers that are not ready.
+func setDependencyReady(status *nephiov1alpha1.NFDeploymentStatus, nfDeployment *nephiov1alpha1.NFDeployment,
+	peerTypes []controllers.NFType, peers map[controllers.NFType][]nephiov1alpha1.NFDeployment) bool {
+	if len(peerTypes) == 0 {
+		Set(status, nfDeployment, ConditionDependencyReady, metav1.ConditionTrue, "NoDependencies", "The NF has no dependencies")
+		return true
+	}
+	ready := true
+	var notReady, missing []string
+	for _, peerType := range peerTypes {
+		typeReady := false
+		for i := range peers[peerType] {
+			peer := &peers[peerType][i]
+			if meta.IsStatusConditionTrue(peer.Status.Conditions, ConditionReady) {
+				typeReady = true
+			} else {
+				notReady = append(notReady, peer.Name)
+			}
+		}
+		if len(peers[peerType]) == 0 {
+			missing = append(missing, strings.ToUpper(string(peerType)))
+		}
+		ready = ready && typeReady
+	}
+	switch {
+	case len(missing) > 0:
+		Set(status, nfDeployment, ConditionDependencyReady, metav1.ConditionFalse, "PeersNotFound",
+			fmt.Sprintf("No %s peer found", strings.Join(missing, " or ")))
+	case !ready:
+		Set(status, nfDeployment, ConditionDependencyReady, metav1.ConditionFalse, "PeersNotReady",
+			fmt.Sprintf("Peers not ready: %s", strings.Join(notReady, ", ")))
+	case len(notReady) > 0:
+		Set(status, nfDeployment, ConditionDependencyReady, metav1.ConditionTrue, "PeersReady",
+			fmt.Sprintf("Peers not ready: %s", strings.Join(notReady, ", ")))
+	default:
+		Set(status, nfDeployment, ConditionDependencyReady, metav1.ConditionTrue, "PeersReady", "All peers are ready")
+	}
+	return ready
+}
+
+// setImagesResolved sets the ImagesResolved condition listing the image digests the containers of the running
+// pods were pulled at, by container. It is false until every container has been pulled.
+func setImagesResolved(status *nephiov1alpha1.NFDeploymentStatus, nfDeployment *nephiov1alpha1.NFDeployment,
+	deployment *appsv1.Deployment, pods []apiv1.Pod) {
+	digests := map[string][]string{}
+	pending := false
+	running := 0
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil || pod.Status.Phase != apiv1.PodRunning {
+			continue
+		}
+		running++
+		for _, container := range deployment.Spec.Template.Spec.Containers {
+			imageID := ""
+			for _, containerStatus := range pod.Status.ContainerStatuses {
+				if containerStatus.Name == container.Name {
+					imageID = strings.TrimPrefix(containerStatus.ImageID, "docker-pullable://")
+				}
+			}
+			if imageID == "" {
+				pending = true
+				continue
+			}
+			if !containsString(digests[container.Name], imageID) {
+				digests[container.Name] = append(digests[container.Name], imageID)
+			}
+		}
+	}
+	if running == 0 || pending {
+		Set(status, nfDeployment, ConditionImagesResolved, metav1.ConditionFalse, "ImagesPending",
+			"The images of the running pods are not pulled yet")
+		return
+	}
+
+	var resolved []string
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		resolved = append(resolved, fmt.Sprintf("%s=%s", container.Name, strings.Join(digests[container.Name], ",")))
+	}
+	Set(status, nfDeployment, ConditionImagesResolved, metav1.ConditionTrue, "ImagesResolved", strings.Join(resolved, " "))
+}
+
+// containsString returns true if the value is in the list
+func containsString(values []string, value string) bool {
+	for _, existing := range values {
+		if existing == value {
+			return true
+		}
+	}
+	return false
+}
+
+// desiredReplicas returns the number of pods of the Deployment, 1 unless set
+func desiredReplicas(deployment *appsv1.Deployment) int32 {
+	if deployment.Spec.Replicas != nil {
+		return *deployment.Spec.Replicas
+	}
+	return 1
+}
+
+// Equal returns true if the statuses have the same observed generation and conditions, regardless
+// of the transition times and the order of the conditions
+func Equal(a, b nephiov1alpha1.NFDeploymentStatus) bool {
+	if a.ObservedGeneration != b.ObservedGeneration || len(a.Conditions) != len(b.Conditions) {
+		return false
+	}
+	for _, condition := range a.Conditions {
+		other := meta.FindStatusCondition(b.Conditions, condition.Type)
+		if other == nil || other.Status != condition.Status || other.Reason != condition.Reason ||
+			other.Message != condition.Message || other.ObservedGeneration != condition.ObservedGeneration {
+			return false
+		}
+	}
+	return true
+}
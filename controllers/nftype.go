@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+)
+
+// NFType identifies the SD-Core network function an NFDeployment describes
+type NFType string
+
+// SD-Core network function types whose implementations register themselves in the registry
+const (
+	NFTypeUPF  NFType = "upf"
+	NFTypeSMF  NFType = "smf"
+	NFTypeAMF  NFType = "amf"
+	NFTypeNRF  NFType = "nrf"
+	NFTypeAUSF NFType = "ausf"
+	NFTypeUDM  NFType = "udm"
+	NFTypeUDR  NFType = "udr"
+	NFTypePCF  NFType = "pcf"
+	NFTypeNSSF NFType = "nssf"
+)
+
+const (
+	// NFTypeKey is the well-known label or annotation that pins the NF type of an NFDeployment
+	NFTypeKey = "nf.sdcore.io/type"
+
+	// sdcoreProviderSuffix is the suffix of the per-NF provider strings, e.g. smf.sdcore.io
+	sdcoreProviderSuffix = ".sdcore.io"
+)
+
+// ConditionUnsupported is set on an SD-Core NFDeployment whose NF type cannot be resolved
+const ConditionUnsupported = "Unsupported"
+
+// ErrUnsupportedNFType is returned when the NF type of an NFDeployment cannot be resolved
+var ErrUnsupportedNFType = errors.New("unsupported SD-Core NF type")
+
+// ProviderForNFType returns the per-NF provider string for an NF type, e.g. amf.sdcore.io
+func ProviderForNFType(nfType NFType) string {
+	return string(nfType) + sdcoreProviderSuffix
+}
+
+// ResolveNFType determines the NF type of an SD-Core NFDeployment.
+//
+// The type is taken, in order of precedence, from the nf.sdcore.io/type label or
+// annotation, from a per-NF provider string such as smf.sdcore.io, and finally
+// from the interfaces present in the spec (n2 for AMF, n3/n6 for UPF, n4 only for SMF).
+// Only NF types present in the registry are returned.
+func ResolveNFType(nfDeployment *nephiov1alpha1.NFDeployment) (NFType, error) {
+	for _, values := range []map[string]string{nfDeployment.Labels, nfDeployment.Annotations} {
+		if value, ok := values[NFTypeKey]; ok {
+			nfType := NFType(strings.ToLower(value))
+			if _, ok := registry[nfType]; !ok {
+				return "", fmt.Errorf("%w: %s %q", ErrUnsupportedNFType, NFTypeKey, value)
+			}
+			return nfType, nil
+		}
+	}
+
+	for _, nfType := range RegisteredNFTypes() {
+		if registry[nfType].MatchesProvider(nfDeployment.Spec.Provider) {
+			return nfType, nil
+		}
+	}
+	if strings.HasSuffix(strings.ToLower(nfDeployment.Spec.Provider), sdcoreProviderSuffix) {
+		return "", fmt.Errorf("%w: provider %q", ErrUnsupportedNFType, nfDeployment.Spec.Provider)
+	}
+
+	return resolveNFTypeFromInterfaces(nfDeployment)
+}
+
+// resolveNFTypeFromInterfaces infers the NF type from the reference points present in the spec
+func resolveNFTypeFromInterfaces(nfDeployment *nephiov1alpha1.NFDeployment) (NFType, error) {
+	interfaces := map[string]bool{}
+	for _, iface := range nfDeployment.Spec.Interfaces {
+		interfaces[strings.ToLower(iface.Name)] = true
+	}
+
+	candidates := map[NFType]bool{}
+	if interfaces["n2"] {
+		candidates[NFTypeAMF] = true
+	}
+	if interfaces["n3"] || interfaces["n6"] {
+		candidates[NFTypeUPF] = true
+	}
+	if interfaces["n4"] && !candidates[NFTypeUPF] {
+		candidates[NFTypeSMF] = true
+	}
+	for nfType := range candidates {
+		if _, ok := registry[nfType]; !ok {
+			delete(candidates, nfType)
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return "", fmt.Errorf("%w: no n2, n3, n4 or n6 interface to infer the NF type from, set %s or a per-NF provider",
+			ErrUnsupportedNFType, NFTypeKey)
+	case 1:
+		for nfType := range candidates {
+			return nfType, nil
+		}
+	}
+
+	names := make([]string, 0, len(candidates))
+	for nfType := range candidates {
+		names = append(names, string(nfType))
+	}
+	sort.Strings(names)
+	return "", fmt.Errorf("%w: interfaces match more than one NF type (%s), set %s or a per-NF provider",
+		ErrUnsupportedNFType, strings.Join(names, ", "), NFTypeKey)
+}
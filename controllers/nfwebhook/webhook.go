@@ -0,0 +1,228 @@
+package nfwebhook
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RohitRathore1/sdcore-operator/controllers"
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// +kubebuilder:webhook:path=/mutate-workload-nephio-org-v1alpha1-nfdeployment,mutating=true,failurePolicy=ignore,sideEffects=None,groups=workload.nephio.org,resources=nfdeployments,verbs=create;update,versions=v1alpha1,name=mnfdeployment.sdcore.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-workload-nephio-org-v1alpha1-nfdeployment,mutating=false,failurePolicy=ignore,sideEffects=None,groups=workload.nephio.org,resources=nfdeployments,verbs=create;update,versions=v1alpha1,name=vnfdeployment.sdcore.io,admissionReviewVersions=v1
+
+// NFDeploymentWebhook defaults and validates SD-Core NFDeployments at admission, so that specs the network
+// functions cannot be built from are rejected when they are applied. NFDeployments of other providers are
+// admitted unchanged.
+type NFDeploymentWebhook struct {
+	// Client lists the UPF NFDeployments the UE pools of a UPF must not overlap with and reads the Configs
+	// the parameters are resolved from
+	Client client.Reader
+}
+
+// Sets up the defaulting and validating webhooks with the Manager
+func (w *NFDeploymentWebhook) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(new(nephiov1alpha1.NFDeployment)).
+		WithDefaulter(w).
+		WithValidator(w).
+		Complete()
+}
+
+// Default fills in the spec of an SD-Core NFDeployment, NFDeployments whose NF type cannot be resolved are
+// left to the validation
+func (w *NFDeploymentWebhook) Default(ctx context.Context, obj runtime.Object) error {
+	nfDeployment, ok := obj.(*nephiov1alpha1.NFDeployment)
+	if !ok {
+		return fmt.Errorf("expected an NFDeployment, got %T", obj)
+	}
+	if !controllers.IsProviderSDCore(nfDeployment.Spec.Provider) || nfDeployment.DeletionTimestamp != nil {
+		return nil
+	}
+	nfType, err := controllers.ResolveNFType(nfDeployment)
+	if err != nil {
+		return nil
+	}
+	controllers.DefaultSpec(nfDeployment, nfType)
+	return nil
+}
+
+// ValidateCreate validates a new NFDeployment
+func (w *NFDeploymentWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	nfDeployment, ok := obj.(*nephiov1alpha1.NFDeployment)
+	if !ok {
+		return nil, fmt.Errorf("expected an NFDeployment, got %T", obj)
+	}
+	return nil, w.validate(ctx, nfDeployment)
+}
+
+// ValidateUpdate validates a changed NFDeployment. Updates leaving the spec, the NF type and the UPF dataplane
+// mode unchanged, such as finalizer updates of NFDeployments created before the webhook, are always admitted.
+func (w *NFDeploymentWebhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldNFDeployment, ok := oldObj.(*nephiov1alpha1.NFDeployment)
+	if !ok {
+		return nil, fmt.Errorf("expected an NFDeployment, got %T", oldObj)
+	}
+	nfDeployment, ok := newObj.(*nephiov1alpha1.NFDeployment)
+	if !ok {
+		return nil, fmt.Errorf("expected an NFDeployment, got %T", newObj)
+	}
+	if nfDeployment.DeletionTimestamp != nil ||
+		equality.Semantic.DeepEqual(oldNFDeployment.Spec, nfDeployment.Spec) &&
+			oldNFDeployment.Labels[controllers.NFTypeKey] == nfDeployment.Labels[controllers.NFTypeKey] &&
+			oldNFDeployment.Annotations[controllers.NFTypeKey] == nfDeployment.Annotations[controllers.NFTypeKey] &&
+			oldNFDeployment.Annotations[controllers.UPFModeKey] == nfDeployment.Annotations[controllers.UPFModeKey] {
+		return nil, nil
+	}
+	return nil, w.validate(ctx, nfDeployment)
+}
+
+// ValidateDelete admits every deletion, the teardown is handled by the reconciler
+func (w *NFDeploymentWebhook) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate checks an SD-Core NFDeployment with Validate, against the parameters resolved from the referenced
+// Configs and the other NFDeployments of its namespace. Configs that do not exist yet are left to the reconciler.
+func (w *NFDeploymentWebhook) validate(ctx context.Context, nfDeployment *nephiov1alpha1.NFDeployment) error {
+	if !controllers.IsProviderSDCore(nfDeployment.Spec.Provider) {
+		return nil
+	}
+	params, err := controllers.ResolveParameters(ctx, w.Client, nfDeployment)
+	switch {
+	case k8serrors.IsNotFound(err):
+		params = nil
+	case err != nil:
+		if errs := parametersErrors(nfDeployment, err); errs != nil {
+			return invalid(nfDeployment, errs)
+		}
+		return err
+	}
+	nfDeployments := new(nephiov1alpha1.NFDeploymentList)
+	if err := w.Client.List(ctx, nfDeployments, client.InNamespace(nfDeployment.Namespace)); err != nil {
+		return err
+	}
+	if errs := Validate(nfDeployment, params, nfDeployments.Items); len(errs) > 0 {
+		return invalid(nfDeployment, errs)
+	}
+	return nil
+}
+
+// Validate checks the NF type of an SD-Core NFDeployment resolves and its spec and parameters can be built
+// from, as the validating webhook does. The parameters must hold a valid network identity and pass the checks
+// of the network function, they are not checked when nil. The UE pools of a UPF must not overlap with those of
+// the other UPFs of its namespace among nfDeployments.
+func Validate(nfDeployment *nephiov1alpha1.NFDeployment, params *controllers.Parameters,
+	nfDeployments []nephiov1alpha1.NFDeployment) field.ErrorList {
+	if !controllers.IsProviderSDCore(nfDeployment.Spec.Provider) {
+		return nil
+	}
+	nfType, err := controllers.ResolveNFType(nfDeployment)
+	if err != nil {
+		path, value := nfTypeField(nfDeployment)
+		return field.ErrorList{field.Invalid(path, value, err.Error())}
+	}
+
+	errs := controllers.ValidateSpec(nfDeployment, nfType)
+	if params != nil {
+		errs = append(errs, validateParameters(nfDeployment, nfType, params)...)
+	}
+	if nfType == controllers.NFTypeUPF {
+		errs = append(errs, overlappingPools(nfDeployment, nfDeployments)...)
+	}
+	return errs
+}
+
+// parametersErrors returns the error of parameters that cannot be resolved because a referenced Config is
+// invalid as a field error of the parametersRefs, nil for other errors
+func parametersErrors(nfDeployment *nephiov1alpha1.NFDeployment, err error) field.ErrorList {
+	invalidSpec, ok := controllers.IsInvalidSpec(err)
+	if !ok {
+		return nil
+	}
+	return field.ErrorList{field.Invalid(field.NewPath("spec", "parametersRefs"), configNames(nfDeployment), invalidSpec.Error())}
+}
+
+// validateParameters checks the parameters hold a valid network identity and pass the checks of the network
+// function, if any
+func validateParameters(nfDeployment *nephiov1alpha1.NFDeployment, nfType controllers.NFType,
+	params *controllers.Parameters) field.ErrorList {
+	if _, err := params.NetworkIdentity(); err != nil {
+		return field.ErrorList{field.Invalid(field.NewPath("spec", "parametersRefs"), configNames(nfDeployment), err.Error())}
+	}
+	nf, ok := controllers.LookupNetworkFunction(nfType)
+	if !ok {
+		return nil
+	}
+	validator, ok := nf.(controllers.ParametersValidator)
+	if !ok {
+		return nil
+	}
+	return validator.ValidateParameters(nfDeployment, params)
+}
+
+// configNames returns the names of the Configs referenced by the NFDeployment
+func configNames(nfDeployment *nephiov1alpha1.NFDeployment) []string {
+	var names []string
+	for _, ref := range nfDeployment.Spec.ParametersRefs {
+		if controllers.IsConfigRef(ref) {
+			names = append(names, *ref.Name)
+		}
+	}
+	return names
+}
+
+// overlappingPools reports the UE pools of a UPF overlapping with the UE pools of the other UPFs of its namespace
+// among the NFDeployments
+func overlappingPools(nfDeployment *nephiov1alpha1.NFDeployment, nfDeployments []nephiov1alpha1.NFDeployment) field.ErrorList {
+	pools, _ := controllers.UEPools(nfDeployment)
+	if len(pools) == 0 {
+		return nil
+	}
+
+	var errs field.ErrorList
+	for i := range nfDeployments {
+		other := &nfDeployments[i]
+		if other.Namespace != nfDeployment.Namespace || other.Name == nfDeployment.Name || other.DeletionTimestamp != nil ||
+			!controllers.IsProviderSDCore(other.Spec.Provider) {
+			continue
+		}
+		if nfType, err := controllers.ResolveNFType(other); err != nil || nfType != controllers.NFTypeUPF {
+			continue
+		}
+		otherPools, _ := controllers.UEPools(other)
+		for _, pool := range pools {
+			for _, otherPool := range otherPools {
+				if pool.Prefix.Overlaps(otherPool.Prefix) {
+					errs = append(errs, field.Invalid(pool.Path, pool.Prefix.String(),
+						fmt.Sprintf("overlaps UE pool %s of UPF %s", otherPool.Prefix, other.Name)))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// nfTypeField returns the path and value of the field the NF type of the NFDeployment is resolved from, the
+// nf.sdcore.io/type label or annotation when set, the provider otherwise
+func nfTypeField(nfDeployment *nephiov1alpha1.NFDeployment) (*field.Path, string) {
+	if value, ok := nfDeployment.Labels[controllers.NFTypeKey]; ok {
+		return field.NewPath("metadata", "labels").Key(controllers.NFTypeKey), value
+	}
+	if value, ok := nfDeployment.Annotations[controllers.NFTypeKey]; ok {
+		return field.NewPath("metadata", "annotations").Key(controllers.NFTypeKey), value
+	}
+	return field.NewPath("spec", "provider"), nfDeployment.Spec.Provider
+}
+
+// invalid returns the Invalid API error rejecting the NFDeployment
+func invalid(nfDeployment *nephiov1alpha1.NFDeployment, errs field.ErrorList) error {
+	return k8serrors.NewInvalid(nephiov1alpha1.NFDeploymentGroupVersionKind.GroupKind(), nfDeployment.Name, errs)
+}
@@ -0,0 +1,450 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+	refv1alpha1 "github.com/nephio-project/api/references/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// ConfigRefIndexKey indexes NFDeployments by the names of the ref.nephio.org Configs they reference
+const ConfigRefIndexKey = ".spec.parametersRefs.config"
+
+const (
+	// ReasonInvalidParameters is the InvalidSpec reason for parameters of a referenced Config that cannot be
+	// parsed or are out of range, e.g. an unknown IP family, upgrade strategy or release
+	ReasonInvalidParameters = "InvalidParameters"
+	// ReasonInvalidNetworkIdentity is the InvalidSpec reason for PLMNs, GUAMIs, TAIs or slices that are
+	// malformed or duplicated
+	ReasonInvalidNetworkIdentity = "InvalidNetworkIdentity"
+)
+
+// Parameters are the NF settings resolved from the ref.nephio.org Configs referenced by an
+// NFDeployment. Each Config embeds a document of the form:
+//
+//	plmns:
+//	- mcc: "208"
+//	  mnc: "93"
+//	guamis:
+//	- plmnId: {mcc: "208", mnc: "93"}
+//	  amfId: cafe00
+//	tais:
+//	- plmnId: {mcc: "208", mnc: "93"}
+//	  tac: 1
+//	slices:
+//	- sst: 1
+//	  sd: "010203"
+//	  dnns: [internet]
+//	overrides:
+//	  amf:
+//	    networkName:
+//	      full: SD-Core
+//	  upf:
+//	    log_level: debug
+//	upf:
+//	  mode: dpdk
+//	ueRouting:
+//	  ueRoutingInfo:
+//	  - SUPI: imsi-208930000000003
+//	    PathList:
+//	    - DestinationIP: 10.60.0.0/16
+//	      UPF: [upf-branching, upf-edge]
+//	ipFamilies: [IPv4, IPv6]
+//	images:
+//	  release: "1.6"
+//	  components:
+//	    amf: {tag: rel-1.6.5}
+//	upgrade:
+//	  strategy: Recreate
+//	  progressDeadlineSeconds: 300
+//	networks:
+//	  cniType: macvlan
+//	  master: eth1
+//	  interfaces:
+//	    n3:
+//	      cniType: sriov
+//	      resourceName: intel.com/sriov_netdevice
+//
+// Overrides are merged into the "configuration" section of the NF YAML configuration,
+// or into the root of upf.jsonc for the UPF.
+type Parameters struct {
+	// PLMNs replaces the default PLMN served by the core
+	PLMNs []PLMN `json:"plmns,omitempty"`
+	// GUAMIs replaces the default GUAMI of each PLMN
+	GUAMIs []GUAMI `json:"guamis,omitempty"`
+	// TAIs replaces the default tracking area of each PLMN
+	TAIs []TAI `json:"tais,omitempty"`
+	// Slices replaces the default S-NSSAIs served by the core
+	Slices []Slice `json:"slices,omitempty"`
+	// Overrides holds configuration fragments merged into the rendered configuration, keyed by NF type
+	Overrides map[NFType]map[string]interface{} `json:"overrides,omitempty"`
+	// Networks selects how the NF interfaces are attached to the host network
+	Networks *NetworkParameters `json:"networks,omitempty"`
+	// IPFamilies replaces the IP families derived from the NF interfaces for the SBI and the Services,
+	// primary family first
+	IPFamilies []apiv1.IPFamily `json:"ipFamilies,omitempty"`
+	// UPF holds the UPF dataplane settings
+	UPF *UPFParameters `json:"upf,omitempty"`
+	// UERouting is the SMF UE routing policy rendered as uerouting.yaml
+	UERouting *UERouting `json:"ueRouting,omitempty"`
+	// Images selects the container images, on top of the images selected for the operator
+	Images *ImageSettings `json:"images,omitempty"`
+	// Upgrade selects how new versions of the network functions are rolled out
+	Upgrade *UpgradeParameters `json:"upgrade,omitempty"`
+
+	// Peers are the peer NFDeployments discovered by the reconciler for network functions
+	// implementing PeerDependent, they are not read from Configs
+	Peers map[NFType][]nephiov1alpha1.NFDeployment `json:"-"`
+	// DrainingPeers are the peer NFDeployments being deleted, left out of Peers
+	DrainingPeers map[NFType][]nephiov1alpha1.NFDeployment `json:"-"`
+}
+
+// UPFParameters select the UPF dataplane
+type UPFParameters struct {
+	// Mode is the BESS dataplane mode: af_packet, af_xdp, dpdk, cndp or sim.
+	// The nf.sdcore.io/upf-mode annotation takes precedence.
+	Mode string `json:"mode,omitempty"`
+	// DeviceResource is the AF_XDP device plugin resource the cndp mode allocates the N3 and N6 ports from
+	DeviceResource string `json:"deviceResource,omitempty"`
+}
+
+// UpgradeParameters select how the pods of a new version are rolled out
+type UpgradeParameters struct {
+	// Strategy is the Deployment strategy, RollingUpdate or Recreate
+	Strategy appsv1.DeploymentStrategyType `json:"strategy,omitempty"`
+	// ProgressDeadlineSeconds is the time a new version has to become available before it is rolled back
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+}
+
+// DefaultProgressDeadlineSeconds is the time a new version has to become available by default
+const DefaultProgressDeadlineSeconds = int32(600)
+
+// GetProgressDeadlineSeconds returns the progress deadline of rollouts
+func (u UpgradeParameters) GetProgressDeadlineSeconds() int32 {
+	if u.ProgressDeadlineSeconds == nil {
+		return DefaultProgressDeadlineSeconds
+	}
+	return *u.ProgressDeadlineSeconds
+}
+
+// validate checks the strategy is known and the deadline positive
+func (u UpgradeParameters) validate() error {
+	switch u.Strategy {
+	case "", appsv1.RollingUpdateDeploymentStrategyType, appsv1.RecreateDeploymentStrategyType:
+	default:
+		return fmt.Errorf("unknown strategy %q, expected RollingUpdate or Recreate", u.Strategy)
+	}
+	if u.ProgressDeadlineSeconds != nil && *u.ProgressDeadlineSeconds <= 0 {
+		return fmt.Errorf("progressDeadlineSeconds must be positive")
+	}
+	return nil
+}
+
+// GetUpgrade returns the upgrade settings
+func (p *Parameters) GetUpgrade() UpgradeParameters {
+	if p == nil || p.Upgrade == nil {
+		return UpgradeParameters{}
+	}
+	return *p.Upgrade
+}
+
+// GetPeers returns the peer NFDeployments of an NF type
+func (p *Parameters) GetPeers(nfType NFType) []nephiov1alpha1.NFDeployment {
+	if p == nil {
+		return nil
+	}
+	return p.Peers[nfType]
+}
+
+// GetDrainingPeers returns the peer NFDeployments of an NF type being deleted
+func (p *Parameters) GetDrainingPeers(nfType NFType) []nephiov1alpha1.NFDeployment {
+	if p == nil {
+		return nil
+	}
+	return p.DrainingPeers[nfType]
+}
+
+// GetUERouting returns the UE routing policy, empty when none is configured
+func (p *Parameters) GetUERouting() UERouting {
+	if p == nil || p.UERouting == nil {
+		return UERouting{}
+	}
+	return *p.UERouting
+}
+
+// GetUPF returns the UPF dataplane settings
+func (p *Parameters) GetUPF() UPFParameters {
+	if p == nil || p.UPF == nil {
+		return UPFParameters{}
+	}
+	return *p.UPF
+}
+
+// GetImage returns the container image of a component
+func (p *Parameters) GetImage(component string) string {
+	if p == nil || p.Images == nil {
+		return ImageSettings{}.Image(component).String()
+	}
+	return p.Images.Image(component).String()
+}
+
+// GetRelease returns the release bundle the images are taken from
+func (p *Parameters) GetRelease() string {
+	if p == nil || p.Images == nil {
+		return DefaultRelease
+	}
+	return p.Images.GetRelease()
+}
+
+// SetImageDefaults sets the images selected for the operator, the images selected by the Configs
+// take precedence
+func (p *Parameters) SetImageDefaults(defaults ImageSettings) {
+	if p.Images == nil {
+		p.Images = &ImageSettings{}
+	}
+	merged := defaults.Merge(*p.Images)
+	p.Images = &merged
+}
+
+// GetSlices returns the configured slices, or DefaultSlices when none are configured
+func (p *Parameters) GetSlices() []Slice {
+	if p == nil || len(p.Slices) == 0 {
+		return DefaultSlices
+	}
+	return p.Slices
+}
+
+// NetworkIdentity returns the validated network identity. Without configured GUAMIs or TAIs
+// each served PLMN gets one with DefaultAMFID and DefaultTAC.
+func (p *Parameters) NetworkIdentity() (NetworkIdentity, error) {
+	identity := NetworkIdentity{
+		PLMNs:  []PLMN{DefaultPLMN},
+		Slices: p.GetSlices(),
+	}
+	if p != nil {
+		if len(p.PLMNs) > 0 {
+			identity.PLMNs = p.PLMNs
+		}
+		identity.GUAMIs = p.GUAMIs
+		identity.TAIs = p.TAIs
+	}
+	if len(identity.GUAMIs) == 0 {
+		for _, plmn := range identity.PLMNs {
+			identity.GUAMIs = append(identity.GUAMIs, GUAMI{PLMN: plmn, AMFID: DefaultAMFID})
+		}
+	}
+	if len(identity.TAIs) == 0 {
+		for _, plmn := range identity.PLMNs {
+			identity.TAIs = append(identity.TAIs, TAI{PLMN: plmn, TAC: DefaultTAC})
+		}
+	}
+
+	if err := identity.Validate(); err != nil {
+		return NetworkIdentity{}, &InvalidSpecError{
+			Reason: ReasonInvalidNetworkIdentity,
+			Err:    fmt.Errorf("invalid network identity: %w", err),
+		}
+	}
+	return identity, nil
+}
+
+// GetOverrides returns the configuration overrides for an NF type
+func (p *Parameters) GetOverrides(nfType NFType) map[string]interface{} {
+	if p == nil {
+		return nil
+	}
+	return p.Overrides[nfType]
+}
+
+// merge merges other into the parameters, values in other take precedence
+func (p *Parameters) merge(other *Parameters) {
+	if len(other.PLMNs) > 0 {
+		p.PLMNs = other.PLMNs
+	}
+	if len(other.GUAMIs) > 0 {
+		p.GUAMIs = other.GUAMIs
+	}
+	if len(other.TAIs) > 0 {
+		p.TAIs = other.TAIs
+	}
+	if len(other.Slices) > 0 {
+		p.Slices = other.Slices
+	}
+	for nfType, overrides := range other.Overrides {
+		if p.Overrides == nil {
+			p.Overrides = map[NFType]map[string]interface{}{}
+		}
+		if p.Overrides[nfType] == nil {
+			p.Overrides[nfType] = map[string]interface{}{}
+		}
+		mergeMaps(p.Overrides[nfType], overrides)
+	}
+	if other.UERouting != nil {
+		p.UERouting = other.UERouting
+	}
+	if len(other.IPFamilies) > 0 {
+		p.IPFamilies = other.IPFamilies
+	}
+	if other.Upgrade != nil {
+		if p.Upgrade == nil {
+			p.Upgrade = &UpgradeParameters{}
+		}
+		if other.Upgrade.Strategy != "" {
+			p.Upgrade.Strategy = other.Upgrade.Strategy
+		}
+		if other.Upgrade.ProgressDeadlineSeconds != nil {
+			p.Upgrade.ProgressDeadlineSeconds = other.Upgrade.ProgressDeadlineSeconds
+		}
+	}
+	if other.Images != nil {
+		var merged ImageSettings
+		if p.Images != nil {
+			merged = *p.Images
+		}
+		merged = merged.Merge(*other.Images)
+		p.Images = &merged
+	}
+	if other.UPF != nil {
+		if p.UPF == nil {
+			p.UPF = &UPFParameters{}
+		}
+		if other.UPF.Mode != "" {
+			p.UPF.Mode = other.UPF.Mode
+		}
+		if other.UPF.DeviceResource != "" {
+			p.UPF.DeviceResource = other.UPF.DeviceResource
+		}
+	}
+	if other.Networks != nil {
+		if p.Networks == nil {
+			p.Networks = &NetworkParameters{}
+		}
+		p.Networks.merge(other.Networks)
+	}
+}
+
+// IsConfigRef returns true if the object reference points at a ref.nephio.org Config
+func IsConfigRef(ref nephiov1alpha1.ObjectReference) bool {
+	return ref.Kind == "Config" && ref.APIVersion == refv1alpha1.GroupVersion.String() && ref.Name != nil
+}
+
+// ResolveParameters fetches the Configs referenced by the NFDeployment and merges them in order
+func ResolveParameters(ctx context.Context, c client.Reader, nfDeployment *nephiov1alpha1.NFDeployment) (*Parameters, error) {
+	var configs []refv1alpha1.Config
+	for _, ref := range nfDeployment.Spec.ParametersRefs {
+		if !IsConfigRef(ref) {
+			continue
+		}
+
+		config := new(refv1alpha1.Config)
+		key := types.NamespacedName{Namespace: nfDeployment.Namespace, Name: *ref.Name}
+		if err := c.Get(ctx, key, config); err != nil {
+			return nil, fmt.Errorf("failed to get Config %s: %w", key, err)
+		}
+		configs = append(configs, *config)
+	}
+	return MergeConfigs(configs)
+}
+
+// MergeConfigs merges the parameters embedded in Configs in order, the parameters of later Configs take precedence.
+// Parameters that cannot be parsed or are invalid are reported as an InvalidSpecError.
+func MergeConfigs(configs []refv1alpha1.Config) (*Parameters, error) {
+	params := &Parameters{}
+	for i := range configs {
+		config := &configs[i]
+		key := client.ObjectKeyFromObject(config)
+
+		configParams := new(Parameters)
+		if len(config.Spec.Config.Raw) > 0 {
+			if err := json.Unmarshal(config.Spec.Config.Raw, configParams); err != nil {
+				return nil, invalidParameters(key, err)
+			}
+		}
+		if err := validateIPFamilies(configParams.IPFamilies); err != nil {
+			return nil, invalidParameters(key, err)
+		}
+		if configParams.Upgrade != nil {
+			if err := configParams.Upgrade.validate(); err != nil {
+				return nil, invalidParameters(key, fmt.Errorf("upgrade: %w", err))
+			}
+		}
+		if configParams.Images != nil {
+			if err := configParams.Images.Validate(); err != nil {
+				return nil, invalidParameters(key, fmt.Errorf("images: %w", err))
+			}
+		}
+		params.merge(configParams)
+	}
+	return params, nil
+}
+
+// invalidParameters returns an InvalidSpecError for invalid parameters in a Config
+func invalidParameters(key types.NamespacedName, err error) error {
+	return &InvalidSpecError{
+		Reason: ReasonInvalidParameters,
+		Err:    fmt.Errorf("invalid parameters in Config %s: %w", key, err),
+	}
+}
+
+// MergeYAMLConfig merges overrides into the configuration section of a rendered YAML NF configuration
+func MergeYAMLConfig(document string, overrides map[string]interface{}) (string, error) {
+	if len(overrides) == 0 {
+		return document, nil
+	}
+
+	config := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(document), &config); err != nil {
+		return "", err
+	}
+	section, ok := config["configuration"].(map[string]interface{})
+	if !ok {
+		section = map[string]interface{}{}
+		config["configuration"] = section
+	}
+	mergeMaps(section, overrides)
+
+	merged, err := yaml.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	return string(merged), nil
+}
+
+// MergeJSONConfig merges overrides into the root of a rendered JSON NF configuration
+func MergeJSONConfig(document string, overrides map[string]interface{}) (string, error) {
+	if len(overrides) == 0 {
+		return document, nil
+	}
+
+	config := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(document), &config); err != nil {
+		return "", err
+	}
+	mergeMaps(config, overrides)
+
+	merged, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(merged), nil
+}
+
+// mergeMaps deep merges src into dst, nested maps are merged and any other value replaces the one in dst
+func mergeMaps(dst, src map[string]interface{}) {
+	for key, value := range src {
+		srcMap, srcIsMap := value.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			mergeMaps(dstMap, srcMap)
+			continue
+		}
+		dst[key] = value
+	}
+}
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"testing"
+
+	refv1alpha1 "github.com/nephio-project/api/references/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// TestInvalidParameters checks invalid Configs and network identities are reported as an InvalidSpecError,
+// which the reconciler does not retry
+func TestInvalidParameters(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		reason string
+	}{
+		{name: "malformed", config: `{"plmns": "208-93"}`, reason: ReasonInvalidParameters},
+		{name: "ip family", config: `{"ipFamilies": ["IPv5"]}`, reason: ReasonInvalidParameters},
+		{name: "strategy", config: `{"upgrade": {"strategy": "BlueGreen"}}`, reason: ReasonInvalidParameters},
+		{name: "release", config: `{"images": {"release": "0.1"}}`, reason: ReasonInvalidParameters},
+		{name: "mcc", config: `{"plmns": [{"mcc": "20", "mnc": "93"}]}`, reason: ReasonInvalidNetworkIdentity},
+		{name: "sd", config: `{"slices": [{"sst": 1, "sd": "xyz"}]}`, reason: ReasonInvalidNetworkIdentity},
+		{name: "duplicate slice", config: `{"slices": [{"sst": 1, "sd": "010203"}, {"sst": 1, "sd": "010203"}]}`,
+			reason: ReasonInvalidNetworkIdentity},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := refv1alpha1.Config{
+				ObjectMeta: metav1.ObjectMeta{Name: "parameters", Namespace: "default"},
+				Spec:       refv1alpha1.ConfigSpec{Config: runtime.RawExtension{Raw: []byte(test.config)}},
+			}
+			params, err := MergeConfigs([]refv1alpha1.Config{config})
+			if err == nil {
+				_, err = params.NetworkIdentity()
+			}
+			invalid, ok := IsInvalidSpec(err)
+			if !ok {
+				t.Fatalf("got %v, want an InvalidSpecError", err)
+			}
+			if invalid.Reason != test.reason {
+				t.Errorf("got reason %s, want %s", invalid.Reason, test.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// PeerSelectorKey is the annotation holding the label selector of the peer NFDeployments an NF is built
+	// from, e.g. the UPFs of an SMF. Without it the peers are taken from the namespace of the NFDeployment.
+	PeerSelectorKey = "nf.sdcore.io/peer-selector"
+
+	// PeersAnnotation is the Deployment annotation listing the peer NFDeployments, as <namespace>/<name>,
+	// the configuration of its pod template is built from
+	PeersAnnotation = "nf.sdcore.io/peers"
+)
+
+// PeerDependent is implemented by network functions whose configuration is built from other NFDeployments
+type PeerDependent interface {
+	// PeerTypes returns the NF types of the peer NFDeployments
+	PeerTypes() []NFType
+}
+
+// peerSelector returns the label selector of the peers, nil when peers are selected by namespace
+func peerSelector(nfDeployment *nephiov1alpha1.NFDeployment) (labels.Selector, error) {
+	value, ok := nfDeployment.Annotations[PeerSelectorKey]
+	if !ok {
+		return nil, nil
+	}
+	selector, err := labels.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: %w", PeerSelectorKey, value, err)
+	}
+	return selector, nil
+}
+
+// IsPeerOf returns true if the NFDeployment selects the peer, either by its peer selector
+// or by sharing its namespace
+func IsPeerOf(nfDeployment, peer *nephiov1alpha1.NFDeployment) bool {
+	if nfDeployment.Namespace == peer.Namespace && nfDeployment.Name == peer.Name {
+		return false
+	}
+	selector, err := peerSelector(nfDeployment)
+	if err != nil {
+		return false
+	}
+	if selector == nil {
+		return nfDeployment.Namespace == peer.Namespace
+	}
+	return selector.Matches(labels.Set(peer.Labels))
+}
+
+// DependsOn returns true if the network function is built from NFDeployments of the NF type
+func DependsOn(nf NetworkFunction, nfType NFType) bool {
+	dependent, ok := nf.(PeerDependent)
+	if !ok {
+		return false
+	}
+	for _, peerType := range dependent.PeerTypes() {
+		if peerType == nfType {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolvePeers lists the peer NFDeployments of a network function and the peers being deleted by NF type,
+// see SelectPeers and SelectDrainingPeers
+func ResolvePeers(ctx context.Context, c client.Reader, nfDeployment *nephiov1alpha1.NFDeployment,
+	nf NetworkFunction) (peers, draining map[NFType][]nephiov1alpha1.NFDeployment, err error) {
+	if _, ok := nf.(PeerDependent); !ok {
+		return nil, nil, nil
+	}
+
+	selector, err := peerSelector(nfDeployment)
+	if err != nil {
+		return nil, nil, err
+	}
+	var opts []client.ListOption
+	if selector == nil {
+		opts = append(opts, client.InNamespace(nfDeployment.Namespace))
+	} else {
+		opts = append(opts, client.MatchingLabelsSelector{Selector: selector})
+	}
+
+	nfDeployments := new(nephiov1alpha1.NFDeploymentList)
+	if err := c.List(ctx, nfDeployments, opts...); err != nil {
+		return nil, nil, fmt.Errorf("failed to list peer NFDeployments: %w", err)
+	}
+	return SelectPeers(nfDeployment, nf, nfDeployments.Items), SelectDrainingPeers(nfDeployment, nf, nfDeployments.Items), nil
+}
+
+// SelectPeers returns the peers of a network function among the NFDeployments by NF type, sorted by namespace
+// and name. NFDeployments being deleted are left out.
+func SelectPeers(nfDeployment *nephiov1alpha1.NFDeployment, nf NetworkFunction, nfDeployments []nephiov1alpha1.NFDeployment) map[NFType][]nephiov1alpha1.NFDeployment {
+	return selectPeers(nfDeployment, nf, nfDeployments, false)
+}
+
+// SelectDrainingPeers returns the peers of a network function among the NFDeployments that are being deleted,
+// by NF type. The network function stops using them while they drain.
+func SelectDrainingPeers(nfDeployment *nephiov1alpha1.NFDeployment, nf NetworkFunction, nfDeployments []nephiov1alpha1.NFDeployment) map[NFType][]nephiov1alpha1.NFDeployment {
+	return selectPeers(nfDeployment, nf, nfDeployments, true)
+}
+
+// selectPeers returns the peers of a network function among the NFDeployments by NF type, either those
+// being deleted or the others
+func selectPeers(nfDeployment *nephiov1alpha1.NFDeployment, nf NetworkFunction, nfDeployments []nephiov1alpha1.NFDeployment,
+	deleted bool) map[NFType][]nephiov1alpha1.NFDeployment {
+	if _, ok := nf.(PeerDependent); !ok {
+		return nil
+	}
+
+	peers := map[NFType][]nephiov1alpha1.NFDeployment{}
+	for _, peer := range nfDeployments {
+		if (peer.DeletionTimestamp != nil) != deleted || !IsPeerOf(nfDeployment, &peer) || !IsProviderSDCore(peer.Spec.Provider) {
+			continue
+		}
+		peerType, err := ResolveNFType(&peer)
+		if err != nil || !DependsOn(nf, peerType) {
+			continue
+		}
+		peers[peerType] = append(peers[peerType], peer)
+	}
+	for _, list := range peers {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Namespace != list[j].Namespace {
+				return list[i].Namespace < list[j].Namespace
+			}
+			return list[i].Name < list[j].Name
+		})
+	}
+	return peers
+}
+
+// PeerKey returns the <namespace>/<name> key of a peer in the PeersAnnotation
+func PeerKey(peer *nephiov1alpha1.NFDeployment) string {
+	return peer.Namespace + "/" + peer.Name
+}
+
+// WithPeers records the peers of a peer dependent network function on the Deployments among the objects,
+// nil peers leave them unchanged
+func WithPeers(objects []client.Object, peers map[NFType][]nephiov1alpha1.NFDeployment) []client.Object {
+	if peers == nil {
+		return objects
+	}
+	var keys []string
+	for _, list := range peers {
+		for i := range list {
+			keys = append(keys, PeerKey(&list[i]))
+		}
+	}
+	sort.Strings(keys)
+	for _, object := range objects {
+		if deployment, ok := object.(*appsv1.Deployment); ok {
+			annotations := deployment.GetAnnotations()
+			if annotations == nil {
+				annotations = map[string]string{}
+			}
+			annotations[PeersAnnotation] = strings.Join(keys, ",")
+			deployment.SetAnnotations(annotations)
+		}
+	}
+	return objects
+}
+
+// BuiltFrom returns true if the peers recorded on the Deployment include the peer
+func BuiltFrom(deployment *appsv1.Deployment, peer *nephiov1alpha1.NFDeployment) bool {
+	value, ok := deployment.Annotations[PeersAnnotation]
+	if !ok {
+		// Deployments applied before the peers were recorded may be built from any peer
+		return true
+	}
+	key := PeerKey(peer)
+	for _, recorded := range strings.Split(value, ",") {
+		if recorded == key {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveDependents lists the NFDeployments built from the NFDeployment of an NF type, e.g. the SMFs of a UPF,
+// including those being deleted
+func ResolveDependents(ctx context.Context, c client.Reader, nfDeployment *nephiov1alpha1.NFDeployment, nfType NFType) ([]nephiov1alpha1.NFDeployment, error) {
+	nfDeployments := new(nephiov1alpha1.NFDeploymentList)
+	if err := c.List(ctx, nfDeployments); err != nil {
+		return nil, fmt.Errorf("failed to list dependent NFDeployments: %w", err)
+	}
+
+	var dependents []nephiov1alpha1.NFDeployment
+	for _, dependent := range nfDeployments.Items {
+		if !IsProviderSDCore(dependent.Spec.Provider) || !IsPeerOf(&dependent, nfDeployment) {
+			continue
+		}
+		dependentType, err := ResolveNFType(&dependent)
+		if err != nil {
+			continue
+		}
+		if nf, ok := LookupNetworkFunction(dependentType); ok && DependsOn(nf, nfType) {
+			dependents = append(dependents, dependent)
+		}
+	}
+	return dependents, nil
+}
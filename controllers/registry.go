@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NetworkFunction is implemented by each SD-Core NF package and registered at init
+type NetworkFunction interface {
+	// Type returns the NF type implemented
+	Type() NFType
+
+	// MatchesProvider returns true if the provider explicitly selects this NF
+	MatchesProvider(provider string) bool
+
+	// BuildResources returns the desired ConfigMaps, Deployment and Services for the NFDeployment
+	// and the parameters resolved from its Config references.
+	// The Deployment must be named GetNamespacedName(nfDeployment, string(Type())).
+	BuildResources(nfDeployment *nephiov1alpha1.NFDeployment, params *Parameters) ([]client.Object, error)
+}
+
+// StatusReporter is implemented by network functions reporting conditions of their own, next to the
+// conditions every NFDeployment reports
+type StatusReporter interface {
+	// ReportStatus sets the conditions of the network function on the status from its Deployment and
+	// the pods of the Deployment
+	ReportStatus(status *nephiov1alpha1.NFDeploymentStatus, nfDeployment *nephiov1alpha1.NFDeployment,
+		deployment *appsv1.Deployment, pods []apiv1.Pod)
+}
+
+// Drainable is implemented by network functions drained in order before their NFDeployment is deleted: the
+// NFDeployments built from them first roll out a configuration without them, then their pods are scaled down
+type Drainable interface {
+	// DrainTimeout bounds the time waited for the NFDeployments built from the network function
+	DrainTimeout() time.Duration
+}
+
+// SpecValidator is implemented by network functions rejecting NFDeployment specs at admission that they
+// cannot be built from, next to the checks of ValidateSpec
+type SpecValidator interface {
+	// ValidateSpec returns the errors of the NFDeployment spec
+	ValidateSpec(nfDeployment *nephiov1alpha1.NFDeployment) field.ErrorList
+}
+
+// ParametersValidator is implemented by network functions rejecting NFDeployments at admission whose
+// parameters, resolved from their Config references, they cannot be built from
+type ParametersValidator interface {
+	// ValidateParameters returns the errors of the NFDeployment and its parameters
+	ValidateParameters(nfDeployment *nephiov1alpha1.NFDeployment, params *Parameters) field.ErrorList
+}
+
+// SpecDefaulter is implemented by network functions filling in NFDeployment specs at admission
+type SpecDefaulter interface {
+	// DefaultSpec sets the fields of the NFDeployment spec left out
+	DefaultSpec(nfDeployment *nephiov1alpha1.NFDeployment)
+}
+
+// registry holds the registered network functions by NF type
+var registry = map[NFType]NetworkFunction{}
+
+// Register adds a network function to the registry, it panics if the NF type is already registered
+func Register(nf NetworkFunction) {
+	if _, ok := registry[nf.Type()]; ok {
+		panic(fmt.Sprintf("network function %q already registered", nf.Type()))
+	}
+	registry[nf.Type()] = nf
+}
+
+// LookupNetworkFunction returns the registered network function for an NF type
+func LookupNetworkFunction(nfType NFType) (NetworkFunction, bool) {
+	nf, ok := registry[nfType]
+	return nf, ok
+}
+
+// RegisteredNFTypes returns the registered NF types in alphabetical order
+func RegisteredNFTypes() []NFType {
+	nfTypes := make([]NFType, 0, len(registry))
+	for nfType := range registry {
+		nfTypes = append(nfTypes, nfType)
+	}
+	sort.Slice(nfTypes, func(i, j int) bool { return nfTypes[i] < nfTypes[j] })
+	return nfTypes
+}
+
+// ParseNFTypes parses a comma-separated list of NF types, e.g. "upf,smf,amf".
+// An empty list selects every registered NF type.
+func ParseNFTypes(value string) ([]NFType, error) {
+	if strings.TrimSpace(value) == "" {
+		return RegisteredNFTypes(), nil
+	}
+
+	var nfTypes []NFType
+	for _, name := range strings.Split(value, ",") {
+		nfType := NFType(strings.ToLower(strings.TrimSpace(name)))
+		if nfType == "" {
+			continue
+		}
+		if _, ok := registry[nfType]; !ok {
+			return nil, fmt.Errorf("unknown NF type %q, registered types are %v", name, RegisteredNFTypes())
+		}
+		nfTypes = append(nfTypes, nfType)
+	}
+	return nfTypes, nil
+}
+
+// MatchesSDCoreProvider returns true if the provider is the per-NF provider of the NF type
+func MatchesSDCoreProvider(nfType NFType, provider string) bool {
+	return strings.EqualFold(provider, ProviderForNFType(nfType))
+}
@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"fmt"
+	"net/netip"
+
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// standardNetworkInstances are the Nephio network instances interfaces are placed in when the NFDeployment
+// does not place them, by interface name
+var standardNetworkInstances = map[string]string{
+	"n2": "vpc-ran",
+	"n3": "vpc-ran",
+	"n4": "vpc-internal",
+	"n6": "vpc-internet",
+}
+
+// UEPool is a UE address pool of a data network of an NFDeployment
+type UEPool struct {
+	// DataNetwork is the name of the data network the pool belongs to
+	DataNetwork string
+	Prefix      netip.Prefix
+	// Path is the path of the pool in the NFDeployment
+	Path *field.Path
+}
+
+// UEPools returns the UE address pools of the data networks of the NFDeployment. Pools that are not
+// a network prefix are left out and reported as errors.
+func UEPools(nfDeployment *nephiov1alpha1.NFDeployment) ([]UEPool, field.ErrorList) {
+	var pools []UEPool
+	var errs field.ErrorList
+	networkInstancesPath := field.NewPath("spec", "networkInstances")
+	for i, networkInstance := range nfDeployment.Spec.NetworkInstances {
+		for j, dataNetwork := range networkInstance.DataNetworks {
+			name := ""
+			if dataNetwork.Name != nil {
+				name = *dataNetwork.Name
+			}
+			for k, pool := range dataNetwork.Pool {
+				path := networkInstancesPath.Index(i).Child("dataNetworks").Index(j).Child("pool").Index(k).Child("prefix")
+				prefix, err := netip.ParsePrefix(pool.Prefix)
+				if err != nil {
+					errs = append(errs, field.Invalid(path, pool.Prefix, "expected a prefix in CIDR notation"))
+					continue
+				}
+				if prefix != prefix.Masked() {
+					errs = append(errs, field.Invalid(path, pool.Prefix,
+						fmt.Sprintf("host bits are set, expected the network prefix %s", prefix.Masked())))
+					continue
+				}
+				pools = append(pools, UEPool{DataNetwork: name, Prefix: prefix, Path: path})
+			}
+		}
+	}
+	return pools, errs
+}
+
+// RequireInterfaces reports the interfaces the network function cannot run without that are missing from
+// the NFDeployment or have no address
+func RequireInterfaces(nfDeployment *nephiov1alpha1.NFDeployment, names ...string) field.ErrorList {
+	var errs field.ErrorList
+	for _, name := range names {
+		iface := FindInterface(nfDeployment, name)
+		if iface == nil ||
+			(iface.IPv4 == nil || iface.IPv4.Address == "") && (iface.IPv6 == nil || iface.IPv6.Address == "") {
+			errs = append(errs, field.Required(field.NewPath("spec", "interfaces"),
+				fmt.Sprintf("interface %s with an IPv4 or IPv6 address is required", name)))
+		}
+	}
+	return errs
+}
+
+// ValidateSpec checks the NFDeployment spec before any resource is built from it: the interface addresses
+// are well formed, the UE pools are network prefixes that do not overlap, and the checks of the network
+// function pass
+func ValidateSpec(nfDeployment *nephiov1alpha1.NFDeployment, nfType NFType) field.ErrorList {
+	var errs field.ErrorList
+	interfacesPath := field.NewPath("spec", "interfaces")
+	for i := range nfDeployment.Spec.Interfaces {
+		iface := &nfDeployment.Spec.Interfaces[i]
+		if err := ValidateInterface(iface); err != nil {
+			errs = append(errs, field.Invalid(interfacesPath.Index(i), iface.Name, err.Error()))
+		}
+	}
+
+	pools, poolErrs := UEPools(nfDeployment)
+	errs = append(errs, poolErrs...)
+	for i := range pools {
+		for j := 0; j < i; j++ {
+			if pools[i].Prefix.Overlaps(pools[j].Prefix) {
+				errs = append(errs, field.Invalid(pools[i].Path, pools[i].Prefix.String(),
+					fmt.Sprintf("overlaps UE pool %s of data network %s", pools[j].Prefix, pools[j].DataNetwork)))
+			}
+		}
+	}
+
+	if nf, ok := LookupNetworkFunction(nfType); ok {
+		if validator, ok := nf.(SpecValidator); ok {
+			errs = append(errs, validator.ValidateSpec(nfDeployment)...)
+		}
+	}
+	return errs
+}
+
+// DefaultSpec fills in the NFDeployment spec: interfaces that are in no network instance are placed in
+// the standard network instance of their reference point, then the defaults of the network function apply
+func DefaultSpec(nfDeployment *nephiov1alpha1.NFDeployment, nfType NFType) {
+	placed := map[string]bool{}
+	for _, networkInstance := range nfDeployment.Spec.NetworkInstances {
+		for _, name := range networkInstance.Interfaces {
+			placed[name] = true
+		}
+	}
+	for _, iface := range nfDeployment.Spec.Interfaces {
+		name, ok := standardNetworkInstances[iface.Name]
+		if !ok || placed[iface.Name] {
+			continue
+		}
+		networkInstance := findNetworkInstance(nfDeployment, name)
+		if networkInstance == nil {
+			nfDeployment.Spec.NetworkInstances = append(nfDeployment.Spec.NetworkInstances,
+				nephiov1alpha1.NetworkInstance{Name: name})
+			networkInstance = &nfDeployment.Spec.NetworkInstances[len(nfDeployment.Spec.NetworkInstances)-1]
+		}
+		networkInstance.Interfaces = append(networkInstance.Interfaces, iface.Name)
+		placed[iface.Name] = true
+	}
+
+	if nf, ok := LookupNetworkFunction(nfType); ok {
+		if defaulter, ok := nf.(SpecDefaulter); ok {
+			defaulter.DefaultSpec(nfDeployment)
+		}
+	}
+}
+
+// findNetworkInstance returns the network instance with the given name from the NFDeployment spec
+func findNetworkInstance(nfDeployment *nephiov1alpha1.NFDeployment, name string) *nephiov1alpha1.NetworkInstance {
+	for i := range nfDeployment.Spec.NetworkInstances {
+		if nfDeployment.Spec.NetworkInstances[i].Name == name {
+			return &nfDeployment.Spec.NetworkInstances[i]
+		}
+	}
+	return nil
+}
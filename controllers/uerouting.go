@@ -0,0 +1,50 @@
+package controllers
+
+// UERouting is the SMF UE routing policy: the UPF paths of UEs for uplink classification and
+// edge breakout, the route profiles and the packet flow descriptions of applications
+type UERouting struct {
+	// UERoutingInfo selects the UPF path per destination for a UE
+	UERoutingInfo []UERoutingInfo `json:"ueRoutingInfo,omitempty"`
+	// RouteProfile maps route profiles to forwarding policies
+	RouteProfile []RouteProfile `json:"routeProfile,omitempty"`
+	// PFDDataForApp holds the packet flow descriptions of applications
+	PFDDataForApp []PFDDataForApp `json:"pfdDataForApp,omitempty"`
+}
+
+// UERoutingInfo is the routing of a UE
+type UERoutingInfo struct {
+	// SUPI identifies the UE, e.g. imsi-208930000000003
+	SUPI string `json:"SUPI"`
+	// AN is the address of the access network node serving the UE
+	AN string `json:"AN,omitempty"`
+	// PathList are the UPF paths taken per destination
+	PathList []RoutingPath `json:"PathList,omitempty"`
+}
+
+// RoutingPath is the chain of UPFs, by NFDeployment name, traffic to a destination goes through
+type RoutingPath struct {
+	// DestinationIP is the destination prefix in CIDR notation
+	DestinationIP string `json:"DestinationIP"`
+	// UPF lists the UPFs from the branching UPF to the anchor UPF
+	UPF []string `json:"UPF"`
+}
+
+// RouteProfile maps a route profile to a forwarding policy
+type RouteProfile struct {
+	RouteProfileID     string `json:"RouteProfileID"`
+	ForwardingPolicyID int    `json:"ForwardingPolicyID"`
+}
+
+// PFDDataForApp holds the packet flow descriptions of an application
+type PFDDataForApp struct {
+	ApplicationID string `json:"applicationId"`
+	PFDs          []PFD  `json:"pfds"`
+}
+
+// PFD is a packet flow description
+type PFD struct {
+	PFDID            string   `json:"pfdID"`
+	FlowDescriptions []string `json:"flowDescriptions,omitempty"`
+	URLs             []string `json:"urls,omitempty"`
+	DomainNames      []string `json:"domainNames,omitempty"`
+}
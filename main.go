@@ -2,13 +2,18 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	"github.com/RohitRathore1/sdcore-operator/controllers"
 	"github.com/RohitRathore1/sdcore-operator/controllers/nf"
+	"github.com/RohitRathore1/sdcore-operator/controllers/nfwebhook"
 	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
 	refv1alpha1 "github.com/nephio-project/api/references/v1alpha1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -24,6 +29,9 @@ import (
 var (
 	scheme   = runtime.NewScheme()
 	setupLog = controllerruntime.Log.WithName("setup")
+
+	// webhookCertDir is the directory the webhook server reads its serving certificate from
+	webhookCertDir = filepath.Join(os.TempDir(), "k8s-webhook-server", "serving-certs")
 )
 
 func init() {
@@ -32,15 +40,43 @@ func init() {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "render" {
+		if err := render(os.Args[2:], os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	var metricsAddress string
 	var healthProbeAddress string
 	var leaderElect bool
+	var enableWebhooks bool
+	var enabledNFs string
+	var release string
+	var imageRegistry string
+	var imageOverrides stringList
 
 	flag.StringVar(&metricsAddress, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&healthProbeAddress, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&leaderElect, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", os.Getenv("ENABLE_WEBHOOKS") != "false",
+		"Serve the NFDeployment defaulting and validating webhooks on port 9443, "+
+			"the serving certificate is read from "+webhookCertDir+" and the webhooks are not served without it. "+
+			"Disabled when $ENABLE_WEBHOOKS is false.")
+	flag.StringVar(&enabledNFs, "enabled-nfs", "",
+		"Comma-separated list of NF types to reconcile, e.g. upf,smf,amf. "+
+			"All registered NF types are reconciled when empty.")
+	flag.StringVar(&release, "sdcore-release", os.Getenv("SDCORE_RELEASE"),
+		"SD-Core release bundle the images are taken from, "+controllers.DefaultRelease+" when empty. "+
+			"Defaults to $SDCORE_RELEASE.")
+	flag.StringVar(&imageRegistry, "image-registry", os.Getenv("SDCORE_IMAGE_REGISTRY"),
+		"Registry replacing the registry of every image, e.g. a mirror. Defaults to $SDCORE_IMAGE_REGISTRY.")
+	flag.Var(&imageOverrides, "image",
+		"Image of a component as <component>=<image>, e.g. amf=registry.example.com/omecproject/5gc-amf:rel-1.6.5. "+
+			"May be repeated, adds to the comma-separated list in $SDCORE_IMAGES.")
 
 	zapOptions := zap.Options{
 		Development: true,
@@ -75,14 +111,42 @@ func main() {
 		fail(err, "Not able to register Config.ref kind")
 	}
 
+	nfTypes, err := controllers.ParseNFTypes(enabledNFs)
+	if err != nil {
+		fail(err, "invalid --enabled-nfs")
+	}
+	setupLog.Info("enabled network functions", "nfTypes", nfTypes)
+
+	images, err := imageSettings(release, imageRegistry, imageOverrides)
+	if err != nil {
+		fail(err, "invalid image settings")
+	}
+	setupLog.Info("selected images", "release", images.GetRelease(), "registry", images.Registry)
+
+	// Without cert-manager the serving certificate is not mounted, the operator then runs without webhooks
+	if enableWebhooks && !webhookCertsPresent(webhookCertDir) {
+		setupLog.Info("webhook serving certificate not found, not serving the webhooks", "certDir", webhookCertDir)
+		enableWebhooks = false
+	}
+
 	if err = (&nf.NFDeploymentReconciler{
-		Client: manager.GetClient(),
-		Scheme: manager.GetScheme(),
+		Client:     manager.GetClient(),
+		Scheme:     manager.GetScheme(),
+		EnabledNFs: nfTypes,
+		Images:     images,
 	}).SetupWithManager(manager); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "NFDeployment")
 		os.Exit(1)
 	}
 
+	if enableWebhooks {
+		if err := (&nfwebhook.NFDeploymentWebhook{
+			Client: manager.GetClient(),
+		}).SetupWithManager(manager); err != nil {
+			fail(err, "unable to create webhook", "webhook", "NFDeployment")
+		}
+	}
+
 	//+kubebuilder:scaffold:builder
 
 	if err := manager.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -91,6 +155,11 @@ func main() {
 	if err := manager.AddReadyzCheck("readyz", healthz.Ping); err != nil {
 		fail(err, "unable to set up ready check")
 	}
+	if enableWebhooks {
+		if err := manager.AddReadyzCheck("webhook", manager.GetWebhookServer().StartedChecker()); err != nil {
+			fail(err, "unable to set up webhook ready check")
+		}
+	}
 
 	setupLog.Info("starting manager")
 	if err := manager.Start(controllerruntime.SetupSignalHandler()); err != nil {
@@ -98,6 +167,45 @@ func main() {
 	}
 }
 
+// webhookCertsPresent returns true if the serving certificate and key of the webhooks are in the directory
+func webhookCertsPresent(dir string) bool {
+	for _, name := range []string{"tls.crt", "tls.key"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// imageSettings returns the validated image settings selected by the flags, the overrides in $SDCORE_IMAGES
+// come before those of the flags
+func imageSettings(release, registry string, overrides stringList) (controllers.ImageSettings, error) {
+	if value := os.Getenv("SDCORE_IMAGES"); value != "" {
+		overrides = append(strings.Split(value, ","), overrides...)
+	}
+	components, err := controllers.ParseImageOverrides(overrides)
+	if err != nil {
+		return controllers.ImageSettings{}, fmt.Errorf("invalid --image: %w", err)
+	}
+	images := controllers.ImageSettings{Release: release, Registry: registry, Components: components}
+	if err := images.Validate(); err != nil {
+		return controllers.ImageSettings{}, err
+	}
+	return images, nil
+}
+
+// stringList is a flag that may be repeated
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
 func fail(err error, msg string, keysAndValues ...any) {
 	setupLog.Error(err, msg, keysAndValues...)
 	os.Exit(1)
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/RohitRathore1/sdcore-operator/controllers"
+	"github.com/RohitRathore1/sdcore-operator/controllers/nf"
+	"github.com/RohitRathore1/sdcore-operator/controllers/nfwebhook"
+	nephiov1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+	refv1alpha1 "github.com/nephio-project/api/references/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/yaml"
+)
+
+// renderInput holds the objects read by the render command
+type renderInput struct {
+	nfDeployments []nephiov1alpha1.NFDeployment
+	configs       []refv1alpha1.Config
+}
+
+// render prints the resources the operator creates for the SD-Core NFDeployments of the input, e.g.
+//
+//	manager render -f test/upf.yaml -f test/parameters.yaml
+//
+// The Configs and other NFDeployments of the input are the parametersRefs and peers of the NFDeployments,
+// documents of other kinds are ignored. NFDeployments are defaulted and validated as at admission.
+func render(args []string, stdin io.Reader, stdout io.Writer) error {
+	var files stringList
+	var namespace string
+	var release string
+	var imageRegistry string
+	var imageOverrides stringList
+
+	flags := flag.NewFlagSet("render", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s render [flags]\n\n"+
+			"Prints the resources the operator creates for the SD-Core NFDeployments read from the files.\n\n",
+			os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Var(&files, "f", "File with NFDeployments and ref.nephio.org Configs, - for the standard input. "+
+		"May be repeated, the standard input is read when none is given.")
+	flags.StringVar(&namespace, "namespace", "default", "Namespace of the objects of the input without one.")
+	flags.StringVar(&release, "sdcore-release", os.Getenv("SDCORE_RELEASE"),
+		"SD-Core release bundle the images are taken from, as for the operator.")
+	flags.StringVar(&imageRegistry, "image-registry", os.Getenv("SDCORE_IMAGE_REGISTRY"),
+		"Registry replacing the registry of every image, as for the operator.")
+	flags.Var(&imageOverrides, "image", "Image of a component as <component>=<image>, as for the operator.")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments %v, files are given with -f", flags.Args())
+	}
+
+	images, err := imageSettings(release, imageRegistry, imageOverrides)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		files = stringList{"-"}
+	}
+	input := new(renderInput)
+	for _, file := range files {
+		if err := input.readFile(file, stdin, namespace); err != nil {
+			return err
+		}
+	}
+
+	// Every NFDeployment, rendered or peer, is seen as stored after admission
+	admitted := make([]nephiov1alpha1.NFDeployment, 0, len(input.nfDeployments))
+	for i := range input.nfDeployments {
+		admitted = append(admitted, *admit(&input.nfDeployments[i]))
+	}
+
+	var out strings.Builder
+	var errs []error
+	for i := range admitted {
+		nfDeployment := &admitted[i]
+		if !controllers.IsProviderSDCore(nfDeployment.Spec.Provider) {
+			continue
+		}
+		objects, err := renderNFDeployment(nfDeployment, input.configs, admitted, images)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("NFDeployment %s/%s: %w", nfDeployment.Namespace, nfDeployment.Name, err))
+			continue
+		}
+		for _, object := range objects {
+			document, err := marshalObject(object)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(&out, "---\n# Source: NFDeployment %s/%s\n%s", nfDeployment.Namespace, nfDeployment.Name, document)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	_, err = io.WriteString(stdout, out.String())
+	return err
+}
+
+// admit returns a copy of the NFDeployment defaulted as by the admission webhooks, the input is left unchanged
+func admit(nfDeployment *nephiov1alpha1.NFDeployment) *nephiov1alpha1.NFDeployment {
+	admitted := nfDeployment.DeepCopy()
+	if !controllers.IsProviderSDCore(admitted.Spec.Provider) {
+		return admitted
+	}
+	if nfType, err := controllers.ResolveNFType(admitted); err == nil {
+		controllers.DefaultSpec(admitted, nfType)
+	}
+	return admitted
+}
+
+// renderNFDeployment validates an admitted NFDeployment with the checks of the validating webhook, against the
+// parameters of its Configs and the other admitted NFDeployments, then builds its resources with the admitted
+// NFDeployments as peers
+func renderNFDeployment(nfDeployment *nephiov1alpha1.NFDeployment, configs []refv1alpha1.Config,
+	nfDeployments []nephiov1alpha1.NFDeployment, images controllers.ImageSettings) ([]client.Object, error) {
+	params, err := nf.RenderParameters(nfDeployment, configs)
+	if err != nil {
+		return nil, err
+	}
+	if err := nfwebhook.Validate(nfDeployment, params, nfDeployments).ToAggregate(); err != nil {
+		return nil, err
+	}
+	return nf.Render(nfDeployment, configs, nfDeployments, images)
+}
+
+// readFile reads the NFDeployments and Configs of a YAML file holding one or more documents
+func (in *renderInput) readFile(file string, stdin io.Reader, namespace string) error {
+	reader := stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		reader = f
+	}
+
+	documents := utilyaml.NewYAMLReader(bufio.NewReader(reader))
+	for {
+		document, err := documents.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		var typeMeta metav1.TypeMeta
+		if err := yaml.Unmarshal(document, &typeMeta); err != nil {
+			return fmt.Errorf("failed to read %s: %w", file, err)
+		}
+
+		switch typeMeta.GroupVersionKind() {
+		case nephiov1alpha1.NFDeploymentGroupVersionKind:
+			nfDeployment := nephiov1alpha1.NFDeployment{}
+			if err := yaml.UnmarshalStrict(document, &nfDeployment); err != nil {
+				return fmt.Errorf("invalid NFDeployment in %s: %w", file, err)
+			}
+			if nfDeployment.Namespace == "" {
+				nfDeployment.Namespace = namespace
+			}
+			in.nfDeployments = append(in.nfDeployments, nfDeployment)
+		case refv1alpha1.GroupVersion.WithKind("Config"):
+			config := refv1alpha1.Config{}
+			if err := yaml.Unmarshal(document, &config); err != nil {
+				return fmt.Errorf("invalid Config in %s: %w", file, err)
+			}
+			if config.Namespace == "" {
+				config.Namespace = namespace
+			}
+			in.configs = append(in.configs, config)
+		}
+	}
+}
+
+// marshalObject renders a resource as a YAML document with its apiVersion and kind, without the empty status
+// and creation timestamps of objects that were never created
+func marshalObject(object client.Object) ([]byte, error) {
+	gvk, err := apiutil.GVKForObject(object, scheme)
+	if err != nil {
+		return nil, err
+	}
+	object.GetObjectKind().SetGroupVersionKind(gvk)
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(object)
+	if err != nil {
+		return nil, err
+	}
+	unstructured.RemoveNestedField(content, "status")
+	unstructured.RemoveNestedField(content, "metadata", "creationTimestamp")
+	unstructured.RemoveNestedField(content, "spec", "template", "metadata", "creationTimestamp")
+	return yaml.Marshal(content)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// renderFiles renders the files with the default images and returns the output
+func renderFiles(t *testing.T, files []string) string {
+	t.Helper()
+	for _, name := range []string{"SDCORE_RELEASE", "SDCORE_IMAGE_REGISTRY", "SDCORE_IMAGES"} {
+		t.Setenv(name, "")
+	}
+	var args []string
+	for _, file := range files {
+		args = append(args, "-f", file)
+	}
+	var out strings.Builder
+	if err := render(args, strings.NewReader(""), &out); err != nil {
+		t.Fatalf("render %v: %v", files, err)
+	}
+	return out.String()
+}
+
+// sortedDocuments returns the documents of the render output in order
+func sortedDocuments(output string) []string {
+	documents := strings.Split(output, "---\n")
+	sort.Strings(documents)
+	return documents
+}
+
+// TestRenderGolden renders the NFDeployments and Configs of each case together and compares the output with
+// testdata/<case>.golden.yaml, run with -update to regenerate the golden files
+func TestRenderGolden(t *testing.T) {
+	examples, err := filepath.Glob(filepath.Join("test", "*.yaml"))
+	if err != nil || len(examples) == 0 {
+		t.Fatalf("no input files in test: %v", err)
+	}
+	sort.Strings(examples)
+
+	cases := []struct {
+		name  string
+		files []string
+	}{
+		{name: "examples", files: examples},
+		{name: "ulcl", files: []string{filepath.Join("testdata", "ulcl", "input.yaml")}},
+		{name: "upf-dpdk", files: []string{filepath.Join("testdata", "upf-dpdk", "input.yaml")}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := renderFiles(t, c.files)
+			golden := filepath.Join("testdata", c.name+".golden.yaml")
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read %s, run with -update to create it: %v", golden, err)
+			}
+			if got != string(want) {
+				t.Errorf("render output differs from %s, run with -update to regenerate it and review the diff", golden)
+			}
+		})
+	}
+}
+
+// TestRenderInputOrder checks the resources of an NFDeployment do not depend on the order its peers are
+// read in, every NFDeployment is defaulted before any is rendered
+func TestRenderInputOrder(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("test", "*.yaml"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no input files in test: %v", err)
+	}
+	sort.Strings(files)
+	reversed := make([]string, len(files))
+	for i, file := range files {
+		reversed[len(files)-1-i] = file
+	}
+
+	forward := sortedDocuments(renderFiles(t, files))
+	backward := sortedDocuments(renderFiles(t, reversed))
+	if strings.Join(forward, "---\n") != strings.Join(backward, "---\n") {
+		t.Errorf("render output depends on the order of the input files")
+	}
+}
+
+// TestRenderRejects checks NFDeployments the validating webhook rejects fail the render
+func TestRenderRejects(t *testing.T) {
+	upf, err := os.ReadFile(filepath.Join("test", "upf.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "overlapping pools",
+			input: string(upf) + "\n---\n" + strings.Replace(string(upf), "name: test-upf", "name: other-upf", 1),
+			want:  "overlaps UE pool 172.250.0.0/16 of UPF",
+		},
+		{
+			name:  "dataplane mode",
+			input: strings.Replace(string(upf), "name: test-upf", "name: test-upf\n  annotations:\n    nf.sdcore.io/upf-mode: xdp", 1),
+			want:  "xdp",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "input.yaml")
+			if err := os.WriteFile(file, []byte(test.input), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			var out strings.Builder
+			err := render([]string{"-f", file}, strings.NewReader(""), &out)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got %v, want an error containing %q", err, test.want)
+			}
+		})
+	}
+}